@@ -0,0 +1,434 @@
+package cli
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+
+	"github.com/snorkysnark/openalex-chunk-import/converters"
+)
+
+func writeImportScript(sink *converters.FileSink, numChunks int, entityTypes []converters.EntityType) error {
+	if err := os.MkdirAll(sink.Dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(sink.Dir, "duckdb_import.sql"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sink.WriteDuckdbImport(f, entityTypes, numChunks)
+	return nil
+}
+
+// Parses the -format flag into *format
+func fileFormatFlag(format **converters.FileFormat) func(string) error {
+	return func(s string) error {
+		for _, fileFormat := range converters.FileFormats {
+			if fileFormat.Name == s {
+				*format = fileFormat
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown format %q", s)
+	}
+}
+
+// How rows are assigned to the part files of each table
+type partitioning struct {
+	// Number of parts per table, 0 meaning one per goroutine
+	parts int
+	// Route rows by a hash of their record's id instead of splitting the sorted input files into parts
+	byIdHash bool
+}
+
+// Parses the -partition-by flag into *p
+func partitionFlag(p *partitioning) func(string) error {
+	return func(s string) error {
+		mode, count, _ := strings.Cut(s, ":")
+		if mode != "input" && mode != "id-hash" {
+			return fmt.Errorf("unknown partitioning %q", mode)
+		}
+
+		parts, err := strconv.Atoi(count)
+		if err != nil || parts < 1 {
+			return fmt.Errorf("expected %v:N with N at least 1, got %q", mode, s)
+		}
+		p.parts = parts
+		p.byIdHash = mode == "id-hash"
+		return nil
+	}
+}
+
+func findJsonFiles(root string) ([]string, error) {
+	var jsonPaths []string
+
+	if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".gz" {
+			jsonPaths = append(jsonPaths, path)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Already in lexical order per directory, sorted again so the order doesn't depend on the walk
+	slices.Sort(jsonPaths)
+	return jsonPaths, nil
+}
+
+// Splits input files between numChunks goroutines, the last one also getting the remainder
+func splitChunks(jsonPaths []string, numChunks int) [][]string {
+	chunkSize := len(jsonPaths) / numChunks
+	chunkInputs := make([][]string, numChunks)
+
+	for chunk := range numChunks - 1 {
+		chunkInputs[chunk] = jsonPaths[chunk*chunkSize : (chunk+1)*chunkSize]
+	}
+	chunkInputs[numChunks-1] = jsonPaths[(numChunks-1)*chunkSize:]
+	return chunkInputs
+}
+
+func writeFieldAudit(outputPath string, reports map[string]*converters.FieldAuditReport) error {
+	f, err := os.Create(filepath.Join(outputPath, "field_audit.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
+
+func printCastStats(castStats map[string][]converters.CastCounts) {
+	fmt.Println("Type mismatches:")
+	for _, entityType := range converters.EntityTypes {
+		for _, counts := range castStats[entityType.Name] {
+			if wrongType := counts.WrongTypeTotal(); wrongType > 0 {
+				fmt.Printf(
+					"  %v %v %v: expected %v, %v wrong type %v, %v null, %v absent, %v ok\n",
+					entityType.Name, counts.ColumnName(), counts.Path, counts.Expected, wrongType, counts.WrongType, counts.Null, counts.Absent, counts.Ok,
+				)
+			}
+		}
+	}
+}
+
+func writeCastStats(outputPath string, castStats map[string][]converters.CastCounts) error {
+	f, err := os.Create(filepath.Join(outputPath, "cast_stats.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(castStats)
+}
+
+// A part file together with the chunk run that produced it
+type manifestEntry struct {
+	converters.OutputPart
+	Inputs          []string `json:"inputs"`
+	DurationSeconds float64  `json:"duration_seconds"`
+}
+
+func manifestEntries(parts []converters.OutputPart, inputs func(chunk int) []string, duration func(chunk int) time.Duration) []manifestEntry {
+	entries := make([]manifestEntry, 0, len(parts))
+	for _, part := range parts {
+		entries = append(entries, manifestEntry{
+			OutputPart:      part,
+			Inputs:          inputs(part.Chunk),
+			DurationSeconds: duration(part.Chunk).Seconds(),
+		})
+	}
+	return entries
+}
+
+func writeManifest(outputPath string, entries []manifestEntry) error {
+	f, err := os.Create(filepath.Join(outputPath, "output_manifest.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{"parts": entries})
+}
+
+func registerMapping(path string) error {
+	mapping, err := converters.LoadMapping(path)
+	if err != nil {
+		return err
+	}
+	return converters.RegisterMapping(mapping)
+}
+
+// Parses -filter flags of the form ENTITY:EXPRESSION into options
+func filterFlag(options *converters.Options) func(string) error {
+	return func(s string) error {
+		entity, expression, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("expected ENTITY:EXPRESSION, got %q", s)
+		}
+
+		filter, err := converters.ParseFilter(expression)
+		if err != nil {
+			return err
+		}
+		options.AddFilter(entity, filter)
+		return nil
+	}
+}
+
+// Runs the command line tool. Programs registering their own entity types with
+// converters.RegisterEntityType or RegisterMapping can call this from their main function
+// to convert them along with the built-in ones
+func Main() {
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		reprocess(os.Args[2:])
+		return
+	}
+
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), "Usage: main [-flags] INPUT_DIR OUTPUT DIR\n")
+		fmt.Fprint(flag.CommandLine.Output(), "       main reprocess [-flags] ENTITY DEAD_LETTER_FILE... OUTPUT_DIR\n\n")
+		flag.PrintDefaults()
+	}
+	chunksFlag := flag.Int("chunks", 8, "Number of goroutines")
+	options := converters.NewOptions()
+	pipeline := converters.Pipeline{Options: options}
+	flag.IntVar(&pipeline.DecodeWorkers, "decode-workers", 1, "goroutines decoding JSON per chunk, while another one reads and decompresses the input")
+	flag.IntVar(&pipeline.ConvertWorkers, "convert-workers", 1, "goroutines converting records per chunk")
+	flag.BoolVar(&pipeline.TableEncoders, "table-encoders", false, "write every table of a chunk from its own goroutine")
+	flag.IntVar(&pipeline.Buffer, "pipeline-buffer", 1024, "records per chunk that may be in flight between the pipeline stages")
+	auditFieldsFlag := flag.Bool("audit-fields", false, "write a report of JSON fields present in the input but not converted, and vice versa")
+	castStatsFlag := flag.Bool("cast-stats", false, "count fields that are absent, null or of an unexpected type, and write them to cast_stats.json")
+	logCastMismatchesFlag := flag.Bool("log-cast-mismatches", false, "log the first occurrence of every unexpected field type (implies -cast-stats)")
+	flag.BoolVar(&options.TopicsLegacyColumns, "topics-legacy-columns", true, "fill the delimited topics.keywords and raw topics.siblings columns")
+
+	format := converters.FormatCsv
+	flag.Func("format", "output format: csv, jsonl or parquet (default csv)", fileFormatFlag(&format))
+	var partitionBy partitioning
+	flag.Func("partition-by", "input:N to write N parts per table whatever the number of goroutines, giving the same files with any -chunks, or id-hash:N to write all rows of a record to part hash(id) % N (default one part per goroutine)", partitionFlag(&partitionBy))
+	worksByYearFlag := flag.Bool("works-by-year", false, "lay out the works tables as works/<table>/publication_year=YYYY/part-<chunk> for DuckDB's hive partitioning")
+	maxOpenPartitionsFlag := flag.Int("max-open-partitions", 64, "with -works-by-year, year files each goroutine keeps open per table, rows of the other years are held back")
+	partitionBufferRowsFlag := flag.Int("partition-buffer-rows", 10000, "with -works-by-year, rows held back per table for years without an open file before writing them out")
+	flag.Func("mapping", "JSON mapping file adding an entity type or tables, can be repeated", registerMapping)
+	subsetFlag := flag.Bool("subset", false, "only convert the works matching -filter and the records they reference")
+	subsetIdsFlag := flag.String("subset-ids", "", "file with one work id per line to start the subset from (implies -subset)")
+	projection := converters.NewProjection()
+	flag.Func("tables", "comma-separated ENTITY.TABLE, only write these tables of their entity types", projection.SelectTables)
+	flag.Func("columns", "ENTITY.TABLE:COLUMN,... to only write these columns of a table, or ENTITY.TABLE:-COLUMN,... to skip them, can be repeated", projection.SelectColumns)
+	flag.Func("filter", "ENTITY:EXPRESSION, only convert records of ENTITY matching EXPRESSION, can be repeated", filterFlag(options))
+
+	entityTypesMaskSeq := converters.EntityTypeNames
+	flag.Func("entities", "comma-separated entity types", func(s string) error {
+		entityTypesMaskSeq = strings.SplitSeq(s, ",")
+		return nil
+	})
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	inputPath, outputPath := flag.Arg(0), flag.Arg(1)
+	if err := projection.Check(converters.EntityTypes); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	numChunks := *chunksFlag
+	numParts := cmp.Or(partitionBy.parts, numChunks)
+	if *worksByYearFlag && partitionBy.byIdHash {
+		fmt.Fprintln(os.Stderr, "-works-by-year can't be combined with -partition-by id-hash")
+		os.Exit(1)
+	}
+
+	fileSink := converters.NewFileSink(outputPath, format)
+	if *worksByYearFlag {
+		fileSink.HivePartitions["works"] = "publication_year"
+	}
+	fileSink.MaxOpenPartitions = *maxOpenPartitionsFlag
+	fileSink.PartitionBufferRows = *partitionBufferRowsFlag
+	sink := projection.Sink(fileSink)
+	var hashPartitionSink *converters.HashPartitionSink
+	if partitionBy.byIdHash {
+		hashPartitionSink = converters.NewHashPartitionSink(sink, numParts)
+		sink = hashPartitionSink
+	}
+	collectCastStats := *castStatsFlag || *logCastMismatchesFlag
+
+	// Hash set of entity types that need to be converted
+	entityTypeMask := map[string]struct{}{}
+	for typeName := range entityTypesMaskSeq {
+		if _, exists := converters.LookupEntityType(typeName); !exists {
+			fmt.Println("Ignoring unknown entity type", typeName)
+		}
+		entityTypeMask[typeName] = struct{}{}
+	}
+
+	if *subsetFlag || *subsetIdsFlag != "" {
+		if err := prepareSubset(inputPath, *subsetIdsFlag, numChunks, options); err != nil {
+			panic(err)
+		}
+	}
+
+	fieldAuditReports := map[string]*converters.FieldAuditReport{}
+	castStats := map[string][]converters.CastCounts{}
+	manifest := []manifestEntry{}
+
+	// Entity types that had input, the only ones the import script reads
+	var convertedTypes []converters.EntityType
+
+	for _, entityType := range converters.EntityTypes {
+		if _, exists := entityTypeMask[entityType.Name]; !exists {
+			continue
+		}
+		jsonPaths, err := findJsonFiles(filepath.Join(inputPath, entityType.Name))
+		if errors.Is(err, fs.ErrNotExist) {
+			// Older snapshots don't have every entity type
+			fmt.Println("Skipping", entityType.Name, "- no input directory")
+			continue
+		} else if err != nil {
+			panic(err)
+		}
+		fmt.Println("Converting", entityType.Name)
+		convertedTypes = append(convertedTypes, entityType)
+
+		// Hash partitioned parts are written by every goroutine
+		chunkInputs := splitChunks(jsonPaths, numParts)
+		if hashPartitionSink != nil {
+			chunkInputs = splitChunks(jsonPaths, numChunks)
+		}
+
+		// Entity types converted by Go code don't report the fields they read
+		auditFields, typeCastStats := *auditFieldsFlag, collectCastStats
+		if (auditFields || typeCastStats) && !entityType.HasMapping() {
+			fmt.Println("No field audit or cast statistics for", entityType.Name, "- converted by Go code")
+			auditFields, typeCastStats = false, false
+		}
+		options.FieldAudit, options.CastStats = nil, nil
+		if auditFields {
+			options.FieldAudit = converters.NewFieldAudit()
+		}
+		if typeCastStats {
+			options.CastStats = converters.NewCastStats(entityType.Name, *logCastMismatchesFlag)
+		}
+
+		pbPool, err := pb.StartPool()
+		if err != nil {
+			panic(err)
+		}
+
+		entityStart := time.Now()
+		chunkDurations := make([]time.Duration, len(chunkInputs))
+
+		// Every part is converted by a single goroutine, at most numChunks at a time
+		running := make(chan struct{}, numChunks)
+		wg := new(sync.WaitGroup)
+		for chunk, chunkInput := range chunkInputs {
+			progress := pb.New(len(chunkInput))
+			pbPool.Add(progress)
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				running <- struct{}{}
+				defer func() { <-running }()
+				defer progress.Finish()
+				// Records are already guarded individually, this catches failures outside of them
+				// so that the other chunks can still finish
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("%v chunk %v: panic: %v\n%s", entityType.Name, chunk, r, debug.Stack())
+					}
+				}()
+
+				start := time.Now()
+				defer func() { chunkDurations[chunk] = time.Since(start) }()
+
+				if err := pipeline.Convert(entityType, func(yield func(string) bool) {
+					for _, inputPath := range chunkInput {
+						if !yield(inputPath) {
+							return
+						}
+						progress.Increment()
+					}
+				}, sink, chunk); err != nil {
+					log.Println(err)
+				}
+			}()
+		}
+
+		wg.Wait()
+		pbPool.Stop()
+
+		inputs := func(chunk int) []string { return chunkInputs[chunk] }
+		duration := func(chunk int) time.Duration { return chunkDurations[chunk] }
+		if hashPartitionSink != nil {
+			if err := hashPartitionSink.Close(); err != nil {
+				log.Println(err)
+			}
+			entityDuration := time.Since(entityStart)
+			inputs = func(int) []string { return jsonPaths }
+			duration = func(int) time.Duration { return entityDuration }
+		}
+		manifest = append(manifest, manifestEntries(fileSink.TakeParts(), inputs, duration)...)
+
+		if auditFields {
+			report := options.FieldAudit.Report()
+			fmt.Printf("%v: %v unmapped fields, %v dead fields\n", entityType.Name, len(report.Unmapped), len(report.Dead))
+			fieldAuditReports[entityType.Name] = report
+		}
+		if typeCastStats {
+			castStats[entityType.Name] = options.CastStats.Counts()
+		}
+	}
+
+	fmt.Println("Writing import script")
+	if err := writeImportScript(fileSink, numParts, projection.EntityTypes(convertedTypes)); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Writing output manifest")
+	if err := writeManifest(outputPath, manifest); err != nil {
+		panic(err)
+	}
+
+	if *auditFieldsFlag {
+		fmt.Println("Writing field audit")
+		if err := writeFieldAudit(outputPath, fieldAuditReports); err != nil {
+			panic(err)
+		}
+	}
+
+	if collectCastStats {
+		printCastStats(castStats)
+		if err := writeCastStats(outputPath, castStats); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/snorkysnark/openalex-chunk-import/converters"
+)
+
+// Feeds dead letter files back through a converter, writing a single chunk
+// and an import script for that entity type into a fresh output directory
+func reprocess(args []string) {
+	flags := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), "Usage: main reprocess [-flags] ENTITY DEAD_LETTER_FILE... OUTPUT_DIR\n\n")
+		flags.PrintDefaults()
+	}
+	options := converters.NewOptions()
+	flags.BoolVar(&options.TopicsLegacyColumns, "topics-legacy-columns", true, "fill the delimited topics.keywords and raw topics.siblings columns")
+	format := converters.FormatCsv
+	flags.Func("format", "output format: csv, jsonl or parquet (default csv)", fileFormatFlag(&format))
+	worksByYearFlag := flags.Bool("works-by-year", false, "lay out the works tables as works/<table>/publication_year=YYYY/part-<chunk> for DuckDB's hive partitioning")
+	maxOpenPartitionsFlag := flags.Int("max-open-partitions", 64, "with -works-by-year, year files each goroutine keeps open per table, rows of the other years are held back")
+	partitionBufferRowsFlag := flags.Int("partition-buffer-rows", 10000, "with -works-by-year, rows held back per table for years without an open file before writing them out")
+	flags.Func("mapping", "JSON mapping file adding an entity type or tables, can be repeated", registerMapping)
+	projection := converters.NewProjection()
+	flags.Func("tables", "comma-separated ENTITY.TABLE, only write these tables of their entity types", projection.SelectTables)
+	flags.Func("columns", "ENTITY.TABLE:COLUMN,... to only write these columns of a table, or ENTITY.TABLE:-COLUMN,... to skip them, can be repeated", projection.SelectColumns)
+	flags.Func("filter", "ENTITY:EXPRESSION, only convert records of ENTITY matching EXPRESSION, can be repeated", filterFlag(options))
+	flags.Parse(args)
+
+	if flags.NArg() < 3 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	entityName := flags.Arg(0)
+	deadLetterPaths := flags.Args()[1 : flags.NArg()-1]
+	outputPath := flags.Arg(flags.NArg() - 1)
+
+	entityType, ok := converters.LookupEntityType(entityName)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Unknown entity type:", entityName)
+		os.Exit(1)
+	}
+
+	// Chunk 0 of an existing conversion would be overwritten
+	if _, err := os.Stat(filepath.Join(outputPath, entityType.Name)); err == nil {
+		fmt.Fprintln(os.Stderr, "Output already contains", entityType.Name, "- use an empty directory")
+		os.Exit(1)
+	}
+
+	if err := projection.Check(converters.EntityTypes); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fileSink := converters.NewFileSink(outputPath, format)
+	if *worksByYearFlag {
+		fileSink.HivePartitions["works"] = "publication_year"
+	}
+	fileSink.MaxOpenPartitions = *maxOpenPartitionsFlag
+	fileSink.PartitionBufferRows = *partitionBufferRowsFlag
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Writing import script")
+	if err := writeImportScript(fileSink, 1, projection.EntityTypes([]converters.EntityType{entityType})); err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Reprocessing", entityType.Name)
+	start := time.Now()
+	if err := entityType.ConvertWith(converters.ReadDeadLetters(slices.Values(deadLetterPaths)), projection.Sink(fileSink), 0, options); err != nil {
+		log.Println(err)
+	}
+	duration := time.Since(start)
+
+	fmt.Println("Writing output manifest")
+	manifest := manifestEntries(fileSink.TakeParts(), func(int) []string { return deadLetterPaths }, func(int) time.Duration { return duration })
+	if err := writeManifest(outputPath, manifest); err != nil {
+		panic(err)
+	}
+}
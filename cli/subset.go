@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/snorkysnark/openalex-chunk-import/converters"
+)
+
+func readIdList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, scanner.Err()
+}
+
+// Restricts the conversion with options to the works matching their filters (and listed in idsPath, if given)
+// along with the records they reference, reading the input once beforehand to collect the referenced ids
+func prepareSubset(inputPath string, idsPath string, numChunks int, options *converters.Options) error {
+	subset := converters.NewSubset()
+
+	if idsPath != "" {
+		ids, err := readIdList(idsPath)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			subset.Add("works", id)
+		}
+		options.AddFilter("works", subset.Filter("works"))
+	}
+
+	for _, entityType := range converters.EntityTypes {
+		if converters.SubsetRestricts(entityType.Name) {
+			options.AddFilter(entityType.Name, subset.Filter(entityType.Name))
+		}
+	}
+
+	for _, entity := range converters.SubsetScanOrder() {
+		jsonPaths, err := findJsonFiles(filepath.Join(inputPath, entity))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		fmt.Println("Collecting references from", entity)
+
+		wg := new(sync.WaitGroup)
+		for _, chunkInput := range splitChunks(jsonPaths, numChunks) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				subset.Collect(entity, converters.ReadJsonLinesAll(slices.Values(chunkInput)), options)
+			}()
+		}
+		wg.Wait()
+	}
+
+	fmt.Println("Subset:")
+	for _, entityType := range converters.EntityTypes {
+		if converters.SubsetRestricts(entityType.Name) {
+			fmt.Printf("  %v: %v ids\n", entityType.Name, subset.Len(entityType.Name))
+		}
+	}
+	return nil
+}
@@ -1,17 +1,59 @@
 package converters
 
 import (
+	"fmt"
 	"io"
 	"iter"
+	"slices"
 )
 
 type EntityType struct {
-	Name           string
-	Convert        func(gzipPaths iter.Seq[string], outputPath string, chunk int)
-	WriteSqlImport func(w io.Writer, outputPath string, numChunks int)
+	Name   string
+	Tables []TableSchema
+	// Converts every record in lines, writing chunk number chunk of each table to sink.
+	// Only fails if the tables can't be opened, errors of individual records are logged
+	Convert func(lines iter.Seq2[JsonLine, error], sink Sink, chunk int) error
+	// Optional, replaces the import script generated from Tables when writing files
+	WriteSqlImport func(w io.Writer, sink *FileSink, numChunks int)
+
+	// Set for entity types converted by a mapping instead of Go code
+	mapping *Mapping
 }
 
-var EntityTypes = []EntityType{TypeAuthors, TypeTopics, TypeConcepts, TypeInstitutions, TypePublishers, TypeSources, TypeWorks}
+// Built-in entity types, converted according to the mappings in mappings/
+var (
+	TypeAuthors      = mappingEntityType(mustLoadBuiltinMapping("authors"))
+	TypeTopics       = mappingEntityType(mustLoadBuiltinMapping("topics"))
+	TypeKeywords     = mappingEntityType(mustLoadBuiltinMapping("keywords"))
+	TypeDomains      = mappingEntityType(mustLoadBuiltinMapping("domains"))
+	TypeFields       = mappingEntityType(mustLoadBuiltinMapping("fields"))
+	TypeSubfields    = mappingEntityType(mustLoadBuiltinMapping("subfields"))
+	TypeConcepts     = mappingEntityType(mustLoadBuiltinMapping("concepts"))
+	TypeInstitutions = mappingEntityType(mustLoadBuiltinMapping("institutions"))
+	TypePublishers   = mappingEntityType(mustLoadBuiltinMapping("publishers"))
+	TypeSources      = mappingEntityType(mustLoadBuiltinMapping("sources"))
+	TypeFunders      = mappingEntityType(mustLoadBuiltinMapping("funders"))
+	TypeWorks        = mappingEntityType(mustLoadBuiltinMapping("works"))
+)
+
+// Like Convert, with the given options instead of NewOptions().
+// Entity types registered with Go code only get the records that match the filters,
+// the other options need a mapping
+func (entityType EntityType) ConvertWith(lines iter.Seq2[JsonLine, error], sink Sink, chunk int, options *Options) error {
+	if entityType.mapping == nil {
+		return entityType.Convert(options.filterLines(entityType.Name, lines), sink, chunk)
+	}
+	return convertMapping(entityType.mapping, lines, sink, chunk, options)
+}
+
+// Whether the entity type is converted by a mapping, which all of the Options apply to
+func (entityType EntityType) HasMapping() bool {
+	return entityType.mapping != nil
+}
+
+// Registered entity types, converted in this order.
+// Only modify through RegisterEntityType, before any conversion starts
+var EntityTypes = []EntityType{TypeAuthors, TypeTopics, TypeKeywords, TypeDomains, TypeFields, TypeSubfields, TypeConcepts, TypeInstitutions, TypePublishers, TypeSources, TypeFunders, TypeWorks}
 
 func EntityTypeNames(yield func(string) bool) {
 	for _, entityType := range EntityTypes {
@@ -20,3 +62,63 @@ func EntityTypeNames(yield func(string) bool) {
 		}
 	}
 }
+
+func LookupEntityType(name string) (EntityType, bool) {
+	i := slices.IndexFunc(EntityTypes, func(entityType EntityType) bool {
+		return entityType.Name == name
+	})
+	if i < 0 {
+		return EntityType{}, false
+	}
+	return EntityTypes[i], true
+}
+
+// Adds an entity type, or replaces the one with the same name in place.
+// Meant to be called from the init function of packages extending the tool,
+// which the command line tool then converts like the built-in ones, see cli.Main
+func RegisterEntityType(entityType EntityType) error {
+	if entityType.Name == "" || entityType.Convert == nil {
+		return fmt.Errorf("entity type %q needs a name and a Convert function", entityType.Name)
+	}
+
+	i := slices.IndexFunc(EntityTypes, func(existing EntityType) bool {
+		return existing.Name == entityType.Name
+	})
+	if i < 0 {
+		EntityTypes = append(EntityTypes, entityType)
+	} else {
+		EntityTypes[i] = entityType
+	}
+	return nil
+}
+
+// Adds a user supplied mapping.
+// A mapping for a built-in entity type adds its tables to those of the built-in mapping
+// (without an id, which the built-in mapping already checks),
+// one for an entity type added by another mapping replaces it,
+// and one for an unknown entity type adds a new entity type
+func RegisterMapping(mapping *Mapping) error {
+	i := slices.IndexFunc(EntityTypes, func(entityType EntityType) bool {
+		return entityType.Name == mapping.Entity
+	})
+	if i < 0 {
+		return RegisterEntityType(mappingEntityType(mapping))
+	}
+
+	existing := EntityTypes[i].mapping
+	if existing == nil {
+		return fmt.Errorf("mapping %v: entity type is converted by Go code registered with RegisterEntityType", mapping.Entity)
+	}
+	if !existing.builtin {
+		return RegisterEntityType(mappingEntityType(mapping))
+	}
+
+	if mapping.Id != "" {
+		return fmt.Errorf("mapping %v: id is checked by the built-in mapping and can't be set", mapping.Entity)
+	}
+	extended, err := existing.extend(mapping)
+	if err != nil {
+		return err
+	}
+	return RegisterEntityType(mappingEntityType(extended))
+}
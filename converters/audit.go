@@ -0,0 +1,187 @@
+package converters
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Statistics for a single JSON path, like "authorships[].author.id"
+type FieldStats struct {
+	Path     string         `json:"path"`
+	Count    int            `json:"count"`
+	Types    map[string]int `json:"types"`
+	Consumed bool           `json:"consumed"`
+}
+
+type FieldAuditReport struct {
+	Records int          `json:"records"`
+	Fields  []FieldStats `json:"fields"`
+	// Paths present in the input that no converter reads
+	Unmapped []string `json:"unmapped"`
+	// Paths the converters read that never occurred in the input
+	Dead []string `json:"dead"`
+}
+
+// Audit of a conversion, set on Options to collect it
+type FieldAudit struct {
+	mu      sync.Mutex
+	records int
+	seen    map[string]*FieldStats
+	// Paths read by the converters, true if read as a whole JSON value
+	consumed map[string]bool
+	// Paths read as a whole JSON value, whose contents don't need to be walked,
+	// e.g. abstract_inverted_index would otherwise yield a path per word
+	wholeValues sync.Map
+}
+
+// Starts recording the JSON paths seen in the input and read by the converters.
+// Paths differ between entity types, so every entity type needs its own audit
+func NewFieldAudit() *FieldAudit {
+	return &FieldAudit{
+		seen:     map[string]*FieldStats{},
+		consumed: map[string]bool{},
+	}
+}
+
+func (audit *FieldAudit) Report() *FieldAuditReport {
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+
+	report := &FieldAuditReport{Records: audit.records, Unmapped: []string{}, Dead: []string{}}
+
+	for _, path := range slices.Sorted(maps.Keys(audit.seen)) {
+		if audit.insideWholeValue(path) {
+			continue
+		}
+
+		stats := audit.seen[path]
+		stats.Consumed = audit.isMapped(path)
+		report.Fields = append(report.Fields, *stats)
+
+		if !stats.Consumed {
+			report.Unmapped = append(report.Unmapped, path)
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(audit.consumed)) {
+		if _, exists := audit.seen[path]; !exists {
+			report.Dead = append(report.Dead, path)
+		}
+	}
+
+	return report
+}
+
+func parentPath(path string) (string, bool) {
+	if parent, found := strings.CutSuffix(path, "[]"); found {
+		return parent, true
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i], true
+	}
+	return "", false
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return "number"
+	}
+}
+
+// Whether a path lies below one that is written out as raw JSON
+func (a *FieldAudit) insideWholeValue(path string) bool {
+	for parent, ok := parentPath(path); ok; parent, ok = parentPath(parent) {
+		if a.consumed[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// Array elements count as mapped when the array itself is
+func (a *FieldAudit) isMapped(path string) bool {
+	if _, consumed := a.consumed[path]; consumed {
+		return true
+	}
+	if parent, found := strings.CutSuffix(path, "[]"); found {
+		return a.isMapped(parent)
+	}
+	return false
+}
+
+// Counts the paths in a freshly decoded record
+func (a *FieldAudit) observe(data map[string]any) {
+	if a == nil {
+		return
+	}
+
+	seen := map[string]map[string]int{}
+
+	var walk func(value any, path string)
+	walk = func(value any, path string) {
+		if path != "" {
+			if seen[path] == nil {
+				seen[path] = map[string]int{}
+			}
+			seen[path][jsonTypeName(value)]++
+
+			if _, whole := a.wholeValues.Load(path); whole {
+				return
+			}
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			for key, child := range v {
+				walk(child, joinPath(path, key))
+			}
+		case []any:
+			for _, item := range v {
+				walk(item, path+"[]")
+			}
+		}
+	}
+	walk(data, "")
+
+	a.mu.Lock()
+	a.records++
+	for path, types := range seen {
+		stats := a.seen[path]
+		if stats == nil {
+			stats = &FieldStats{Path: path, Types: map[string]int{}}
+			a.seen[path] = stats
+		}
+		for typeName, count := range types {
+			stats.Count += count
+			stats.Types[typeName] += count
+		}
+	}
+	a.mu.Unlock()
+}
+
+// Marks a path as read by a converter
+func (a *FieldAudit) consume(path string, whole bool) {
+	if a == nil {
+		return
+	}
+	if whole {
+		a.wholeValues.Store(path, struct{}{})
+	}
+
+	a.mu.Lock()
+	a.consumed[path] = a.consumed[path] || whole
+	a.mu.Unlock()
+}
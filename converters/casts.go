@@ -0,0 +1,141 @@
+package converters
+
+import (
+	"cmp"
+	"encoding/json"
+	"log"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// How often a JSON path read as a given type for a column was absent, null or of another type
+type CastCounts struct {
+	// Empty for the paths read to find the rows of a table, or for the id of the record
+	Table  string `json:"table,omitempty"`
+	Column string `json:"column,omitempty"`
+
+	Path      string         `json:"path"`
+	Expected  string         `json:"expected"`
+	Ok        int            `json:"ok"`
+	Absent    int            `json:"absent"`
+	Null      int            `json:"null"`
+	WrongType map[string]int `json:"wrong_type"`
+}
+
+// Column the path was read for as table.column, the table alone for the paths
+// finding its rows, or "id" for the id of the record
+func (c *CastCounts) ColumnName() string {
+	switch {
+	case c.Table == "":
+		return "id"
+	case c.Column == "":
+		return c.Table
+	default:
+		return c.Table + "." + c.Column
+	}
+}
+
+func (c *CastCounts) WrongTypeTotal() int {
+	total := 0
+	for _, count := range c.WrongType {
+		total += count
+	}
+	return total
+}
+
+type castKey struct {
+	table    string
+	column   string
+	path     string
+	expected string
+}
+
+// Counts of a conversion, set on Options to collect them
+type CastStats struct {
+	mu            sync.Mutex
+	entity        string
+	logMismatches bool
+	counts        map[castKey]*CastCounts
+}
+
+// Starts counting failed casts for an entity type, logging the first occurrence
+// of every unexpected type if logMismatches is set
+func NewCastStats(entity string, logMismatches bool) *CastStats {
+	return &CastStats{
+		entity:        entity,
+		logMismatches: logMismatches,
+		counts:        map[castKey]*CastCounts{},
+	}
+}
+
+func (s *CastStats) Counts() []CastCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make([]CastCounts, 0, len(s.counts))
+	for _, c := range s.counts {
+		counts = append(counts, *c)
+	}
+	slices.SortFunc(counts, func(a, b CastCounts) int {
+		return cmp.Or(
+			cmp.Compare(a.Table, b.Table), cmp.Compare(a.Column, b.Column),
+			cmp.Compare(a.Path, b.Path), cmp.Compare(a.Expected, b.Expected),
+		)
+	})
+	return counts
+}
+
+func expectedTypeName[T any]() string {
+	var zero T
+	switch any(zero).(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return reflect.TypeFor[T]().String()
+	}
+}
+
+func (s *CastStats) record(key castKey, update func(c *CastCounts)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counts[key]
+	if c == nil {
+		c = &CastCounts{Table: key.table, Column: key.column, Path: key.path, Expected: key.expected, WrongType: map[string]int{}}
+		s.counts[key] = c
+	}
+	update(c)
+}
+
+func (s *CastStats) recordValue(key castKey, value any, exists bool, ok bool) {
+	if s == nil {
+		return
+	}
+
+	s.record(key, func(c *CastCounts) {
+		switch {
+		case ok:
+			c.Ok++
+		case !exists:
+			c.Absent++
+		case value == nil:
+			c.Null++
+		default:
+			got := jsonTypeName(value)
+			c.WrongType[got]++
+
+			if s.logMismatches && c.WrongType[got] == 1 {
+				log.Printf("%v: %v is %v, expected %v for %v (logged once)", s.entity, c.Path, got, c.Expected, c.ColumnName())
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+// Package converters flattens OpenAlex snapshot records into relational tables.
+//
+// Every EntityType converts a stream of JsonLine records and writes its Tables to a Sink.
+// FileSink writes gzipped CSV (the default of the command line tool), gzipped JSON lines or Parquet files
+// along with a DuckDB import script, MemorySink keeps rows in memory,
+// and any other destination can be plugged in by implementing Sink:
+//
+//	sink := converters.NewMemorySink()
+//	err := converters.TypeWorks.Convert(converters.JsonLines(reader, "queue"), sink, 0)
+//	rows := sink.Table("works", "works_authorships").Rows
+//
+// Records can come from anywhere as long as they are decoded with DecodeJsonLine
+// (or JsonLines), which keeps numbers as json.Number.
+// Convert may be called concurrently with different chunk numbers, each chunk
+// being written to separate RowWriters
+package converters
@@ -0,0 +1,635 @@
+package converters
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encodes rows into a part file. close flushes, the file itself is closed by the caller
+type rowEncoder interface {
+	encode(row Row) error
+	close() error
+}
+
+type FileFormat struct {
+	Name       string
+	Extension  string
+	newEncoder func(w io.Writer, table TableSchema) (rowEncoder, error)
+	// DuckDB table function reading a part file, or with hive every file matching a glob,
+	// adding the partition columns of their directories
+	duckdbReader func(path string, table TableSchema, hive bool) string
+}
+
+// A finished <table><chunk> file
+type OutputPart struct {
+	Entity string `json:"entity"`
+	Table  string `json:"table"`
+	Chunk  int    `json:"chunk"`
+	Path   string `json:"path"`
+	// Hive partition directory, like publication_year=2020
+	Partition string `json:"partition,omitempty"`
+	// Files of the same hive partition and chunk are numbered from 0,
+	// there is more than one when the partition was closed to make room for others
+	File int `json:"file,omitempty"`
+	// Data rows, not counting a header
+	Rows int64 `json:"rows"`
+	// Size and SHA-256 of the file as written, so they can be checked with sha256sum
+	Bytes  int64  `json:"bytes"`
+	Sha256 string `json:"sha256"`
+}
+
+// Writes every table and chunk to <dir>/<entity>/<table><chunk><extension>
+// and rejected lines to <dir>/<entity>/<entity>_dead_letter<chunk>.jsonl.gz
+type FileSink struct {
+	Dir    string
+	Format *FileFormat
+	// Top-level record field by entity type, whose tables are instead laid out as
+	// <dir>/<entity>/<table>/<field>=<value>/part-<chunk><extension> for DuckDB's hive partitioning.
+	// Records without the field go to <field>=NULL
+	HivePartitions map[string]string
+	// Hive partitions a chunk of a table keeps open, 64 if not set. Rows of the others are held back
+	// until a chunk of a table has PartitionBufferRows of them, which are then written a partition at a time,
+	// closing the least recently written files and continuing in part-<chunk>-<n><extension> if needed
+	MaxOpenPartitions int
+	// Rows a chunk of a table holds back for hive partitions without an open file, 10000 if not set
+	PartitionBufferRows int
+
+	mu    sync.Mutex
+	parts []OutputPart
+	// Hive partition value of the record being converted, by entity and chunk
+	current map[tableChunkKey]*string
+}
+
+func NewFileSink(dir string, format *FileFormat) *FileSink {
+	return &FileSink{Dir: dir, Format: format, HivePartitions: map[string]string{}, current: map[tableChunkKey]*string{}}
+}
+
+func NewCsvSink(dir string) *FileSink {
+	return NewFileSink(dir, FormatCsv)
+}
+
+// Path a chunk of a table is written to
+func (s *FileSink) TablePath(entity string, table string, chunk int) string {
+	return filepath.Join(s.Dir, entity, fmt.Sprint(table, chunk, s.Format.Extension))
+}
+
+// Path of file number file of a chunk of a hive partition of a table
+func (s *FileSink) HivePartitionPath(entity string, table string, partition string, chunk int, file int) string {
+	name := fmt.Sprint("part-", chunk, s.Format.Extension)
+	if file > 0 {
+		name = fmt.Sprint("part-", chunk, "-", file, s.Format.Extension)
+	}
+	return filepath.Join(s.Dir, entity, table, partition, name)
+}
+
+func (s *FileSink) maxOpenPartitions() int {
+	if s.MaxOpenPartitions > 0 {
+		return s.MaxOpenPartitions
+	}
+	return 64
+}
+
+func (s *FileSink) partitionBufferRows() int {
+	if s.PartitionBufferRows > 0 {
+		return s.PartitionBufferRows
+	}
+	return 10000
+}
+
+// Counts the bytes written through it
+type byteCounter struct {
+	count int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.count += int64(len(p))
+	return len(p), nil
+}
+
+type fileRowWriter struct {
+	sink    *FileSink
+	file    *os.File
+	encoder rowEncoder
+	part    OutputPart
+	bytes   *byteCounter
+	sha256  hash.Hash
+}
+
+func (s *FileSink) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	if field, exists := s.HivePartitions[entity]; exists {
+		return &hiveRowWriter{
+			sink:       s,
+			entity:     entity,
+			table:      table,
+			chunk:      chunk,
+			field:      field,
+			current:    s.currentPartition(entity, chunk),
+			partitions: map[string]*hivePartition{},
+			recent:     list.New(),
+		}, nil
+	}
+
+	return s.openPart(table, OutputPart{Entity: entity, Table: table.Name, Chunk: chunk, Path: s.TablePath(entity, table.Name, chunk)})
+}
+
+// Creates the file of part
+func (s *FileSink) openPart(table TableSchema, part OutputPart) (*fileRowWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(part.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(part.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &byteCounter{}
+	sha := sha256.New()
+	encoder, err := s.Format.newEncoder(io.MultiWriter(file, counter, sha), table)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &fileRowWriter{
+		sink:    s,
+		file:    file,
+		encoder: encoder,
+		part:    part,
+		bytes:   counter,
+		sha256:  sha,
+	}, nil
+}
+
+func (w *fileRowWriter) WriteRow(row Row) error {
+	if err := w.encoder.encode(row); err != nil {
+		return err
+	}
+	w.part.Rows++
+	return nil
+}
+
+func (w *fileRowWriter) Close() error {
+	if err := w.encoder.close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	w.part.Bytes = w.bytes.count
+	w.part.Sha256 = hex.EncodeToString(w.sha256.Sum(nil))
+
+	w.sink.mu.Lock()
+	w.sink.parts = append(w.sink.parts, w.part)
+	w.sink.mu.Unlock()
+	return nil
+}
+
+// Returns the parts closed since the last call, ordered by entity, table, hive partition and chunk
+func (s *FileSink) TakeParts() []OutputPart {
+	s.mu.Lock()
+	parts := s.parts
+	s.parts = nil
+	s.mu.Unlock()
+
+	slices.SortFunc(parts, func(a, b OutputPart) int {
+		return cmp.Or(
+			cmp.Compare(a.Entity, b.Entity), cmp.Compare(a.Table, b.Table),
+			cmp.Compare(a.Partition, b.Partition), cmp.Compare(a.Chunk, b.Chunk), cmp.Compare(a.File, b.File),
+		)
+	})
+	return parts
+}
+
+// Hive partition of the current record of a chunk, shared by all table writers of that chunk
+func (s *FileSink) currentPartition(entity string, chunk int) *string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tableChunkKey{entity: entity, index: chunk}
+	partition := s.current[key]
+	if partition == nil {
+		partition = new(string)
+		s.current[key] = partition
+	}
+	return partition
+}
+
+// Only entity types with hive partitions are routed
+func (s *FileSink) routesRecords(entity string) bool {
+	_, exists := s.HivePartitions[entity]
+	return exists
+}
+
+func (s *FileSink) startRecord(entity string, chunk int, record map[string]any) {
+	field, exists := s.HivePartitions[entity]
+	if !exists {
+		return
+	}
+
+	value := "NULL"
+	switch v := record[field].(type) {
+	case string:
+		value = v
+	case json.Number:
+		value = v.String()
+	case bool:
+		value = strconv.FormatBool(v)
+	}
+	// Keep values from creating other directories
+	value = strings.NewReplacer("/", "_", "\\", "_").Replace(value)
+	if value == "" || value == "." || value == ".." {
+		value = "NULL"
+	}
+
+	*s.currentPartition(entity, chunk) = field + "=" + value
+}
+
+// Writes each row to the hive partition of its record, creating its file on the first row.
+// At most MaxOpenPartitions files are open, rows of other partitions are held back and written
+// together, the partitions with the most rows first, each closing the least recently written file
+type hiveRowWriter struct {
+	sink    *FileSink
+	entity  string
+	table   TableSchema
+	chunk   int
+	field   string
+	current *string
+
+	partitions map[string]*hivePartition
+	// Names of the partitions with an open file, the most recently written first
+	recent *list.List
+	// Rows held back by all partitions
+	held int
+	// Whether a partition was reopened often enough to be logged
+	warned bool
+}
+
+type hivePartition struct {
+	// Nil while the partition has no open file
+	writer *fileRowWriter
+	used   *list.Element
+	// Files created for the partition so far
+	files int
+	// Rows waiting for the partition to be opened again
+	held []Row
+}
+
+// Partitions reopened this many times are logged, since their rows end up spread over as many files
+const hiveReopenWarning = 4
+
+func (w *hiveRowWriter) open(name string) (*fileRowWriter, error) {
+	partition := w.partitions[name]
+	if partition == nil {
+		partition = &hivePartition{}
+		w.partitions[name] = partition
+	}
+	if partition.writer != nil {
+		w.recent.MoveToFront(partition.used)
+		return partition.writer, nil
+	}
+
+	if w.recent.Len() >= w.sink.maxOpenPartitions() {
+		if err := w.closePartition(w.recent.Back().Value.(string)); err != nil {
+			return nil, err
+		}
+	}
+
+	writer, err := w.sink.openPart(w.table, OutputPart{
+		Entity:    w.entity,
+		Table:     w.table.Name,
+		Chunk:     w.chunk,
+		Path:      w.sink.HivePartitionPath(w.entity, w.table.Name, name, w.chunk, partition.files),
+		Partition: name,
+		File:      partition.files,
+	})
+	if err != nil {
+		return nil, err
+	}
+	partition.writer = writer
+	partition.used = w.recent.PushFront(name)
+	partition.files++
+
+	if partition.files == hiveReopenWarning && !w.warned {
+		w.warned = true
+		log.Printf("%v chunk %v: %v opened %v times, there are more hive partitions than MaxOpenPartitions",
+			w.table.Name, w.chunk, name, partition.files)
+	}
+	return writer, nil
+}
+
+func (w *hiveRowWriter) closePartition(name string) error {
+	partition := w.partitions[name]
+	w.recent.Remove(partition.used)
+	writer := partition.writer
+	partition.writer, partition.used = nil, nil
+	return writer.Close()
+}
+
+func (w *hiveRowWriter) WriteRow(row Row) error {
+	name := *w.current
+	partition := w.partitions[name]
+	if partition == nil {
+		partition = &hivePartition{}
+		w.partitions[name] = partition
+	}
+
+	// Partitions are opened while there is room, the others wait for the next flush
+	if partition.writer == nil && (w.recent.Len() >= w.sink.maxOpenPartitions() || len(partition.held) > 0) {
+		partition.held = append(partition.held, slices.Clone(row))
+		w.held++
+		if w.held >= w.sink.partitionBufferRows() {
+			return w.flush()
+		}
+		return nil
+	}
+
+	writer, err := w.open(name)
+	if err != nil {
+		return err
+	}
+	return writer.WriteRow(row)
+}
+
+// Writes the rows held back, the partitions with the most of them first
+func (w *hiveRowWriter) flush() error {
+	var names []string
+	for name, partition := range w.partitions {
+		if len(partition.held) > 0 {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(w.partitions[b].held), len(w.partitions[a].held)), cmp.Compare(a, b))
+	})
+
+	for _, name := range names {
+		partition := w.partitions[name]
+		writer, err := w.open(name)
+		if err != nil {
+			return err
+		}
+		for _, row := range partition.held {
+			if err := writer.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		w.held -= len(partition.held)
+		partition.held = nil
+	}
+	return nil
+}
+
+// Chunks without any rows still write an empty file, so that the import script's glob matches something
+func (w *hiveRowWriter) Close() error {
+	if len(w.partitions) == 0 {
+		if _, err := w.open(w.field + "=NULL"); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	if err := w.flush(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(w.partitions)) {
+		if w.partitions[name].writer != nil {
+			errs = append(errs, w.closePartition(name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *FileSink) OpenDeadLetters(entity string, chunk int) (DeadLetterWriter, error) {
+	return &deadLetterWriter{
+		path: filepath.Join(s.Dir, entity, fmt.Sprint(entity, "_dead_letter", chunk, ".jsonl.gz")),
+	}, nil
+}
+
+// Writes a DuckDB script loading every chunk of the tables of entityTypes
+func (s *FileSink) WriteDuckdbImport(w io.Writer, entityTypes []EntityType, numChunks int) {
+	for _, entityType := range entityTypes {
+		fmt.Fprintf(w, "--%v\n", entityType.Name)
+
+		if entityType.WriteSqlImport != nil {
+			entityType.WriteSqlImport(w, s, numChunks)
+			fmt.Fprintln(w)
+			continue
+		}
+
+		hiveField, hive := s.HivePartitions[entityType.Name]
+
+		for _, table := range entityType.Tables {
+			columnNames := make([]string, len(table.Columns))
+			columnDefinitions := make([]string, len(table.Columns))
+			for i, column := range table.Columns {
+				columnNames[i] = column.Name
+				columnDefinitions[i] = column.Name + " " + column.Type
+			}
+
+			if table.CreateTable {
+				fmt.Fprintf(w, "CREATE TABLE IF NOT EXISTS openalex.%v (%v);\n", table.Name, strings.Join(columnDefinitions, ", "))
+			}
+
+			if hive {
+				fmt.Fprintf(
+					w,
+					"INSERT INTO openalex.%v(%v)\nSELECT * FROM %v;\n",
+					table.Name, strings.Join(columnNames, ", "),
+					s.Format.duckdbReader(filepath.Join(s.Dir, entityType.Name, table.Name, hiveField+"=*", "*"+s.Format.Extension), table, true),
+				)
+				continue
+			}
+
+			for chunk := range numChunks {
+				fmt.Fprintf(
+					w,
+					"INSERT INTO openalex.%v(%v)\nSELECT * FROM %v;\n",
+					table.Name, strings.Join(columnNames, ", "),
+					s.Format.duckdbReader(s.TablePath(entityType.Name, table.Name, chunk), table, false),
+				)
+			}
+		}
+
+		fmt.Fprintln(w)
+	}
+}
+
+// Columns of table, quoted for a SELECT
+func duckdbColumnList(table TableSchema) string {
+	columnNames := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		columnNames[i] = fmt.Sprintf("%q", column.Name)
+	}
+	return strings.Join(columnNames, ", ")
+}
+
+func hiveOption(hive bool) string {
+	if hive {
+		return ", hive_partitioning = true"
+	}
+	return ""
+}
+
+func duckdbColumnTypes(table TableSchema) string {
+	columnTypes := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		columnTypes[i] = fmt.Sprintf("'%v': '%v'", column.Name, column.Type)
+	}
+	return strings.Join(columnTypes, ", ")
+}
+
+// Gzipped writer, closing which flushes the compressed stream
+type gzipRowEncoder struct {
+	archive  *gzip.Writer
+	flush    func() error
+	writeRow func(row Row) error
+}
+
+func (g *gzipRowEncoder) encode(row Row) error {
+	return g.writeRow(row)
+}
+
+func (g *gzipRowEncoder) close() error {
+	if err := g.flush(); err != nil {
+		return err
+	}
+	return g.archive.Close()
+}
+
+// Formats a value for CSV. JSON columns hold the literal null rather than an empty field
+func csvValue(value any, column Column) string {
+	switch v := value.(type) {
+	case nil:
+		if column.Type == "JSON" {
+			return "null"
+		}
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case json.RawMessage:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var FormatCsv = &FileFormat{
+	Name:      "csv",
+	Extension: ".csv.gz",
+	newEncoder: func(w io.Writer, table TableSchema) (rowEncoder, error) {
+		archive := gzip.NewWriter(w)
+		writer := csv.NewWriter(archive)
+
+		header := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			header[i] = column.Name
+		}
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+
+		record := make([]string, len(table.Columns))
+		return &gzipRowEncoder{
+			archive: archive,
+			flush: func() error {
+				writer.Flush()
+				return writer.Error()
+			},
+			writeRow: func(row Row) error {
+				for i, value := range row {
+					record[i] = csvValue(value, table.Columns[i])
+				}
+				return writer.Write(record)
+			},
+		}, nil
+	},
+	duckdbReader: func(path string, table TableSchema, hive bool) string {
+		reader := fmt.Sprintf("read_csv('%v', columns = {%v}%v)", path, duckdbColumnTypes(table), hiveOption(hive))
+		if hive {
+			// Leave out the partition columns
+			return fmt.Sprintf("(SELECT %v FROM %v)", duckdbColumnList(table), reader)
+		}
+		return reader
+	},
+}
+
+var FormatJsonl = &FileFormat{
+	Name:      "jsonl",
+	Extension: ".jsonl.gz",
+	newEncoder: func(w io.Writer, table TableSchema) (rowEncoder, error) {
+		archive := gzip.NewWriter(w)
+
+		// Object keys in column order
+		keys := make([][]byte, len(table.Columns))
+		for i, column := range table.Columns {
+			key, err := json.Marshal(column.Name)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = key
+		}
+
+		var line bytes.Buffer
+		return &gzipRowEncoder{
+			archive: archive,
+			flush:   func() error { return nil },
+			writeRow: func(row Row) error {
+				line.Reset()
+				line.WriteByte('{')
+				for i, value := range row {
+					if i > 0 {
+						line.WriteByte(',')
+					}
+					line.Write(keys[i])
+					line.WriteByte(':')
+
+					raw, err := json.Marshal(value)
+					if err != nil {
+						return err
+					}
+					line.Write(raw)
+				}
+				line.WriteString("}\n")
+
+				_, err := archive.Write(line.Bytes())
+				return err
+			},
+		}, nil
+	},
+	duckdbReader: func(path string, table TableSchema, hive bool) string {
+		reader := fmt.Sprintf("read_json('%v', format = 'newline_delimited', columns = {%v}%v)", path, duckdbColumnTypes(table), hiveOption(hive))
+		if hive {
+			return fmt.Sprintf("(SELECT %v FROM %v)", duckdbColumnList(table), reader)
+		}
+		return reader
+	},
+}
+
+var FileFormats = []*FileFormat{FormatCsv, FormatJsonl, FormatParquet}
@@ -0,0 +1,184 @@
+package converters
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Input lines decoded from JSON records, as ReadJsonLines would give them
+func jsonLines(t *testing.T, records ...string) iter.Seq2[JsonLine, error] {
+	t.Helper()
+
+	lines := make([]JsonLine, len(records))
+	for i, record := range records {
+		data, err := DecodeJsonLine([]byte(record))
+		if err != nil {
+			t.Fatalf("decoding %v: %v", record, err)
+		}
+		lines[i] = JsonLine{Data: data, Source: "test", Line: i + 1, Raw: []byte(record)}
+	}
+
+	return func(yield func(JsonLine, error) bool) {
+		for _, line := range lines {
+			if !yield(line, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Data rows of a gzipped CSV part, without the header
+func readCsvPart(t *testing.T, path string) [][]string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(gzReader).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows[1:]
+}
+
+func TestHivePartitionsShuffledYears(t *testing.T) {
+	const years, numRecords, maxOpen, bufferRows = 30, 2000, 4, 200
+
+	random := rand.New(rand.NewPCG(1, 2))
+	var records []string
+	var recordYears []int
+	for i := range numRecords {
+		recordYears = append(recordYears, 2000+random.IntN(years))
+		records = append(records, fmt.Sprintf(`{"id": "W%v", "publication_year": %v}`, i, recordYears[i]))
+	}
+
+	sink := NewCsvSink(t.TempDir())
+	sink.HivePartitions["works"] = "publication_year"
+	sink.MaxOpenPartitions = maxOpen
+	sink.PartitionBufferRows = bufferRows
+	if err := TypeWorks.ConvertWith(jsonLines(t, records...), sink, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every flush reopens each year at most once, closing a file for every record would make thousands
+	files, rows := 0, 0
+	last := map[string]int{}
+	for _, part := range sink.TakeParts() {
+		if part.Table != "works" {
+			continue
+		}
+		files++
+		for _, row := range readCsvPart(t, part.Path) {
+			rows++
+			var i int
+			fmt.Sscanf(row[0], "W%d", &i)
+			if want := fmt.Sprint("publication_year=", recordYears[i]); part.Partition != want {
+				t.Errorf("%v written to %v", row[0], part.Partition)
+			}
+			// Rows of a year stay in input order across its files
+			if previous, exists := last[part.Partition]; exists && i < previous {
+				t.Errorf("%v: %v after W%v", part.Partition, row[0], previous)
+			}
+			last[part.Partition] = i
+		}
+	}
+	if rows != numRecords {
+		t.Errorf("%v rows, expected %v", rows, numRecords)
+	}
+	if limit := years * (numRecords/bufferRows + 2); files > limit {
+		t.Errorf("%v files, expected at most %v", files, limit)
+	}
+}
+
+func TestHivePartitionsLimitOpenFiles(t *testing.T) {
+	const years, rounds = 10, 3
+
+	tests := []struct {
+		name                string
+		maxOpenPartitions   int
+		partitionBufferRows int
+		// Files each year is split into
+		files int
+	}{
+		{name: "one open", maxOpenPartitions: 1, partitionBufferRows: 1, files: rounds},
+		{name: "fewer open than years", maxOpenPartitions: 3, partitionBufferRows: 1, files: rounds},
+		{name: "fewer open than years, held back until closed", maxOpenPartitions: 3, files: 1},
+		{name: "every year open", maxOpenPartitions: years, partitionBufferRows: 1, files: 1},
+		{name: "default", files: 1},
+	}
+
+	// Cycles through the years, so every year is written to again once the others pushed it out
+	var records []string
+	for round := range rounds {
+		for year := range years {
+			records = append(records, fmt.Sprintf(`{"id": "W%v-%v", "publication_year": %v}`, round, year, 2000+year))
+		}
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sink := NewCsvSink(t.TempDir())
+			sink.HivePartitions["works"] = "publication_year"
+			sink.MaxOpenPartitions = test.maxOpenPartitions
+			sink.PartitionBufferRows = test.partitionBufferRows
+
+			if err := TypeWorks.ConvertWith(jsonLines(t, records...), sink, 0, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			files := map[string]int{}
+			ids := map[string]string{}
+			for _, part := range sink.TakeParts() {
+				if part.Table != "works" {
+					continue
+				}
+
+				if part.File != files[part.Partition] {
+					t.Errorf("%v: file %v, expected %v", part.Path, part.File, files[part.Partition])
+				}
+				files[part.Partition]++
+				if want := sink.HivePartitionPath("works", "works", part.Partition, 0, part.File); part.Path != want {
+					t.Errorf("part written to %v, expected %v", part.Path, want)
+				}
+
+				rows := readCsvPart(t, part.Path)
+				if int64(len(rows)) != part.Rows {
+					t.Errorf("%v: %v rows, manifest says %v", part.Path, len(rows), part.Rows)
+				}
+				for _, row := range rows {
+					ids[row[0]] = filepath.Base(filepath.Dir(part.Path))
+				}
+			}
+
+			if len(files) != years {
+				t.Errorf("%v partitions, expected %v", len(files), years)
+			}
+			for partition, count := range files {
+				if count != test.files {
+					t.Errorf("%v: %v files, expected %v", partition, count, test.files)
+				}
+			}
+			for round := range rounds {
+				for year := range years {
+					id := fmt.Sprintf("W%v-%v", round, year)
+					if want := fmt.Sprint("publication_year=", 2000+year); ids[id] != want {
+						t.Errorf("%v written to %q, expected %v", id, ids[id], want)
+					}
+				}
+			}
+		})
+	}
+}
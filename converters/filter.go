@@ -0,0 +1,414 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A boolean expression over the fields of a record, deciding whether it is converted at all.
+//
+//	publication_year >= 2015 and not is_retracted = true
+//	authorships.institutions.country_code in ["FR", "DE"]
+//	type = "journal" or (type = "repository" and works_count > 1000)
+//
+// Paths are dot-separated keys and pass through arrays, so a comparison holds
+// if it holds for any value the path reaches. Numbers are compared as numbers, strings
+// lexicographically (which orders ISO dates), and values of different types never match.
+// "= null" holds when the path reaches nothing or a null, "!=" is the negation of "="
+type Filter struct {
+	expression string
+	root       filterNode
+}
+
+type filterNode interface {
+	match(record map[string]any) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+type filterOr struct{ left, right filterNode }
+type filterNot struct{ operand filterNode }
+
+// A path compared against one of the literals, which are nil, string, float64 or bool
+type filterComparison struct {
+	path     []string
+	operator string
+	literals []any
+}
+
+func (f filterAnd) match(record map[string]any) bool {
+	return f.left.match(record) && f.right.match(record)
+}
+
+func (f filterOr) match(record map[string]any) bool {
+	return f.left.match(record) || f.right.match(record)
+}
+
+func (f filterNot) match(record map[string]any) bool {
+	return !f.operand.match(record)
+}
+
+func (f filterComparison) match(record map[string]any) bool {
+	matched := false
+	reached := false
+	walkFilterPath(record, f.path, func(value any) bool {
+		reached = true
+		for _, literal := range f.literals {
+			if compareFilterValue(value, f.operator, literal) {
+				matched = true
+				return false
+			}
+		}
+		return true
+	})
+
+	if !reached && f.operator == "=" {
+		return slices.Contains(f.literals, nil)
+	}
+	return matched
+}
+
+// Calls yield with every value reached by path, descending into arrays, until it returns false
+func walkFilterPath(value any, path []string, yield func(value any) bool) bool {
+	if arr, ok := value.([]any); ok {
+		for _, element := range arr {
+			if !walkFilterPath(element, path, yield) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(path) == 0 {
+		return yield(value)
+	}
+
+	m, ok := value.(map[string]any)
+	if !ok {
+		return true
+	}
+	child, ok := m[path[0]]
+	if !ok {
+		return true
+	}
+	return walkFilterPath(child, path[1:], yield)
+}
+
+func compareFilterValue(value any, operator string, literal any) bool {
+	var order int
+	switch literal := literal.(type) {
+	case nil:
+		return operator == "=" && value == nil
+	case bool:
+		v, ok := value.(bool)
+		return ok && operator == "=" && v == literal
+	case string:
+		v, ok := value.(string)
+		if !ok {
+			return false
+		}
+		order = strings.Compare(v, literal)
+	case float64:
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		v, err := number.Float64()
+		if err != nil {
+			return false
+		}
+		switch {
+		case v < literal:
+			order = -1
+		case v > literal:
+			order = 1
+		}
+	}
+
+	switch operator {
+	case "=":
+		return order == 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+	return false
+}
+
+func (f *Filter) Match(record map[string]any) bool {
+	return f.root.match(record)
+}
+
+func (f *Filter) String() string {
+	return f.expression
+}
+
+type filterParser struct {
+	tokens   []string
+	position int
+}
+
+func ParseFilter(expression string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", expression, err)
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.position < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.position])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", expression, err)
+	}
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// Splits an expression into paths, keywords, literals, operators and brackets.
+// Positions in errors are byte offsets
+func tokenizeFilter(expression string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expression); {
+		c, size := utf8.DecodeRuneInString(expression[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case strings.ContainsRune("()[],", c):
+			tokens = append(tokens, string(c))
+			i++
+		case strings.ContainsRune("=!<>", c):
+			operator := expression[i : i+1]
+			if i+1 < len(expression) && expression[i+1] == '=' {
+				operator = expression[i : i+2]
+			}
+			if operator == "!" {
+				return nil, fmt.Errorf("expected != at %v", i)
+			}
+			tokens = append(tokens, operator)
+			i += len(operator)
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at %v", i)
+			}
+			tokens = append(tokens, expression[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(expression) {
+				c, size := utf8.DecodeRuneInString(expression[end:])
+				if unicode.IsSpace(c) || strings.ContainsRune("()[],=!<>\"", c) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, expression[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func (p *filterParser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *filterParser) next() (string, error) {
+	if p.position >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end")
+	}
+	p.position++
+	return p.tokens[p.position-1], nil
+}
+
+func (p *filterParser) expect(token string) error {
+	next, err := p.next()
+	if err != nil {
+		return fmt.Errorf("expected %q: %w", token, err)
+	}
+	if next != token {
+		return fmt.Errorf("expected %q, got %q", token, next)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.position++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.position++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.peek() == "not" {
+		p.position++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{operand}, nil
+	}
+
+	if p.peek() == "(" {
+		p.position++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	path, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if !isFilterPath(path) {
+		return nil, fmt.Errorf("expected a path, got %q", path)
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := filterComparison{path: splitPath(path), operator: operator}
+	switch operator {
+	case "=", "==", "!=", "<", "<=", ">", ">=":
+		literal, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		_, isString := literal.(string)
+		_, isNumber := literal.(float64)
+		if strings.ContainsAny(operator, "<>") && !isString && !isNumber {
+			return nil, fmt.Errorf("%v needs a number or string", operator)
+		}
+		comparison.literals = []any{literal}
+	case "in":
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		for {
+			literal, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			comparison.literals = append(comparison.literals, literal)
+
+			if p.peek() != "," {
+				break
+			}
+			p.position++
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		comparison.operator = "="
+	default:
+		return nil, fmt.Errorf("unknown operator %q", operator)
+	}
+
+	switch comparison.operator {
+	case "==":
+		comparison.operator = "="
+	case "!=":
+		comparison.operator = "="
+		return filterNot{comparison}, nil
+	}
+	return comparison, nil
+}
+
+func (p *filterParser) parseLiteral() (any, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	if strings.HasPrefix(token, "\"") {
+		var s string
+		if err := json.Unmarshal([]byte(token), &s); err != nil {
+			return nil, fmt.Errorf("%v: %w", token, err)
+		}
+		return s, nil
+	}
+
+	var number float64
+	if err := json.Unmarshal([]byte(token), &number); err != nil {
+		return nil, fmt.Errorf("expected a literal, got %q", token)
+	}
+	return number, nil
+}
+
+func isFilterPath(token string) bool {
+	switch token {
+	case "", "and", "or", "not", "in", "null", "true", "false":
+		return false
+	}
+	for _, key := range splitPath(token) {
+		if key == "" {
+			return false
+		}
+		for _, c := range key {
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,324 @@
+package converters
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		expression string
+		tokens     []string
+		err        string
+	}{
+		{expression: "", tokens: nil},
+		{expression: "a.b=1", tokens: []string{"a.b", "=", "1"}},
+		{expression: "  year >= 2015 ", tokens: []string{"year", ">=", "2015"}},
+		{expression: "a!=b", tokens: []string{"a", "!=", "b"}},
+		{expression: "a==b", tokens: []string{"a", "==", "b"}},
+		{expression: "a<b<=c>d", tokens: []string{"a", "<", "b", "<=", "c", ">", "d"}},
+		{expression: `x in ["a","b"]`, tokens: []string{"x", "in", "[", `"a"`, ",", `"b"`, "]"}},
+		{expression: "not (a = 1)", tokens: []string{"not", "(", "a", "=", "1", ")"}},
+		{expression: `s = "a b(c)=\"d\""`, tokens: []string{"s", "=", `"a b(c)=\"d\""`}},
+		{expression: `s = "\\"`, tokens: []string{"s", "=", `"\\"`}},
+		{expression: "n = -1.5e3", tokens: []string{"n", "=", "-1.5e3"}},
+		// U+2026 ends in the byte 0x85 and U+00E0 in 0xA0, which are spaces as runes of their own
+		{expression: `title = "Ä…"`, tokens: []string{"title", "=", `"Ä…"`}},
+		{expression: "title = Ä… ", tokens: []string{"title", "=", "Ä…"}},
+		{expression: "à=à", tokens: []string{"à", "=", "à"}},
+		{expression: "a\u00a0=\u2003b", tokens: []string{"a", "=", "b"}},
+		{expression: "a ! b", err: "expected != at 2"},
+		{expression: "a = b!", err: "expected != at 5"},
+		{expression: `s = "open`, err: "unterminated string at 4"},
+		{expression: `s = "escaped\"`, err: "unterminated string at 4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			tokens, err := tokenizeFilter(test.expression)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("error %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tokens, test.tokens) {
+				t.Errorf("tokens %q, expected %q", tokens, test.tokens)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{expression: "", err: "unexpected end"},
+		{expression: "year", err: "unexpected end"},
+		{expression: "year >=", err: "unexpected end"},
+		{expression: "year ~ 1", err: `unknown operator "~"`},
+		{expression: "year = 1 2", err: `unexpected "2"`},
+		{expression: "year = 1 and", err: "unexpected end"},
+		{expression: "year = 1 or or", err: `expected a path, got "or"`},
+		{expression: "= 1", err: `expected a path, got "="`},
+		{expression: "null = 1", err: `expected a path, got "null"`},
+		{expression: "a..b = 1", err: `expected a path, got "a..b"`},
+		{expression: "a-b = 1", err: `expected a path, got "a-b"`},
+		{expression: "year = nope", err: `expected a literal, got "nope"`},
+		{expression: "year = [1]", err: `expected a literal, got "["`},
+		{expression: "year > true", err: "> needs a number or string"},
+		{expression: "year <= null", err: "<= needs a number or string"},
+		{expression: "(year = 1", err: `expected ")": unexpected end`},
+		{expression: "(year = 1]", err: `expected ")", got "]"`},
+		{expression: "year = 1)", err: `unexpected ")"`},
+		{expression: "type in", err: `expected "[": unexpected end`},
+		{expression: `type in "a"`, err: `expected "[", got "\"a\""`},
+		{expression: "type in []", err: `expected a literal, got "]"`},
+		{expression: `type in ["a",]`, err: `expected a literal, got "]"`},
+		{expression: `type in ["a" "b"]`, err: `expected "]", got "\"b\""`},
+		{expression: `s = "\x"`, err: `"\x": `},
+		{expression: "s = \"open", err: "unterminated string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			filter, err := ParseFilter(test.expression)
+			if err == nil {
+				t.Fatalf("parsed as %v, expected an error", filter)
+			}
+			if !strings.HasPrefix(err.Error(), "filter ") || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %q, expected it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	record := `{
+		"id": "W1",
+		"publication_year": 2018,
+		"publication_date": "2018-05-01",
+		"cited_by_count": 0,
+		"is_retracted": false,
+		"language": null,
+		"type": "article",
+		"title": "Ä… à",
+		"keywords": [],
+		"topics": [null],
+		"ids": {"mag": 123},
+		"authorships": [
+			{"author": {"id": "A1"}, "institutions": [{"country_code": "FR"}, {"country_code": "US"}]},
+			{"author": {"id": "A2"}, "institutions": []},
+			{"author": {"id": "A3"}, "institutions": [{"country_code": null}]}
+		],
+		"concepts": [{"level": 0}, {"level": 2}]
+	}`
+
+	tests := []struct {
+		expression string
+		match      bool
+	}{
+		// Operators
+		{expression: "publication_year = 2018", match: true},
+		{expression: "publication_year == 2018", match: true},
+		{expression: "publication_year = 2018.0", match: true},
+		{expression: "publication_year != 2018", match: false},
+		{expression: "publication_year < 2018", match: false},
+		{expression: "publication_year <= 2018", match: true},
+		{expression: "publication_year > 2017.5", match: true},
+		{expression: "publication_year >= 2019", match: false},
+		{expression: `publication_date >= "2018-01-01"`, match: true},
+		{expression: `publication_date < "2018-05-01"`, match: false},
+		{expression: `type = "article"`, match: true},
+		{expression: `type != "book"`, match: true},
+		{expression: "is_retracted = false", match: true},
+		{expression: "is_retracted != true", match: true},
+		{expression: "ids.mag = 123", match: true},
+		{expression: `title = "Ä… à"`, match: true},
+		{expression: `title in ["à", "Ä… à"]`, match: true},
+		{expression: `title = "Ä…"`, match: false},
+
+		// Values of different types never match
+		{expression: `publication_year = "2018"`, match: false},
+		{expression: `publication_year >= "2000"`, match: false},
+		{expression: "cited_by_count = false", match: false},
+		{expression: "is_retracted = 0", match: false},
+		{expression: `publication_year != "2018"`, match: true},
+
+		// Paths pass through arrays, holding if any value they reach matches
+		{expression: "authorships.author.id = \"A2\"", match: true},
+		{expression: `authorships.institutions.country_code = "US"`, match: true},
+		{expression: `authorships.institutions.country_code = "DE"`, match: false},
+		{expression: "concepts.level > 1", match: true},
+		{expression: "concepts.level < 0", match: false},
+		{expression: "concepts.level >= 0 and concepts.level <= 0", match: true},
+
+		// != is the negation of =, so it holds only if no value matches
+		{expression: `authorships.institutions.country_code != "US"`, match: false},
+		{expression: `authorships.institutions.country_code != "DE"`, match: true},
+		{expression: "concepts.level != 0", match: false},
+
+		// in matches any of its literals
+		{expression: `type in ["book", "article"]`, match: true},
+		{expression: `type in ["book"]`, match: false},
+		{expression: `authorships.institutions.country_code in ["DE", "FR"]`, match: true},
+		{expression: `authorships.institutions.country_code in ["DE", "IT"]`, match: false},
+		{expression: "publication_year in [2017, 2018]", match: true},
+		{expression: `not type in ["book", "article"]`, match: false},
+
+		// = null holds for missing fields, nulls and paths that reach nothing
+		{expression: "language = null", match: true},
+		{expression: "missing = null", match: true},
+		{expression: "ids.missing = null", match: true},
+		{expression: "keywords = null", match: true},
+		{expression: "keywords.id = null", match: true},
+		{expression: "topics = null", match: true},
+		{expression: "type = null", match: false},
+		{expression: "authorships.institutions.country_code = null", match: true},
+		{expression: "authorships.author.id = null", match: false},
+		{expression: "language != null", match: false},
+		{expression: "missing != null", match: false},
+		{expression: "type != null", match: true},
+		{expression: `missing in [null, "x"]`, match: true},
+		{expression: `missing in ["x"]`, match: false},
+		{expression: `language in ["x", null]`, match: true},
+		{expression: `type in ["x", null]`, match: false},
+
+		// Missing fields only match null
+		{expression: `missing = "x"`, match: false},
+		{expression: `missing != "x"`, match: true},
+		{expression: "missing < 1", match: false},
+		{expression: "missing >= 1", match: false},
+
+		// Paths can't go through values that aren't objects
+		{expression: "type.length = 7", match: false},
+		{expression: "type.length = null", match: true},
+
+		// Boolean operators and precedence
+		{expression: "not is_retracted = true", match: true},
+		{expression: "not not is_retracted = true", match: false},
+		{expression: `type = "book" or type = "article"`, match: true},
+		{expression: `type = "book" or type = "article" and publication_year > 2020`, match: false},
+		{expression: `(type = "book" or type = "article") and publication_year > 2000`, match: true},
+		{expression: `type = "article" or publication_year > 2020 and type = "book"`, match: true},
+		{expression: `not (type = "book" or is_retracted = true)`, match: true},
+		{expression: `not type = "book" and not type = "article"`, match: false},
+	}
+
+	data, err := DecodeJsonLine([]byte(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			filter, err := ParseFilter(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match := filter.Match(data); match != test.match {
+				t.Errorf("Match = %v, expected %v", match, test.match)
+			}
+			if filter.String() != test.expression {
+				t.Errorf("String = %q, expected the expression", filter.String())
+			}
+		})
+	}
+}
+
+func TestOptionsFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string][]string
+		// Ids of the works converted
+		ids []string
+	}{
+		{name: "no filters", ids: []string{"W1", "W2", "W3"}},
+		{name: "one filter", filters: map[string][]string{"works": {"publication_year >= 2010"}}, ids: []string{"W2", "W3"}},
+		{
+			name:    "every filter must match",
+			filters: map[string][]string{"works": {"publication_year >= 2010", `type = "article"`}},
+			ids:     []string{"W3"},
+		},
+		{name: "other entity types", filters: map[string][]string{"authors": {"works_count > 100"}}, ids: []string{"W1", "W2", "W3"}},
+	}
+
+	records := []string{
+		`{"id": "W1", "publication_year": 2000, "type": "article"}`,
+		`{"id": "W2", "publication_year": 2010, "type": "book"}`,
+		`{"id": "W3", "publication_year": 2020, "type": "article"}`,
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := NewOptions()
+			for entity, expressions := range test.filters {
+				for _, expression := range expressions {
+					filter, err := ParseFilter(expression)
+					if err != nil {
+						t.Fatal(err)
+					}
+					options.AddFilter(entity, filter)
+				}
+			}
+
+			sink := NewMemorySink()
+			if err := TypeWorks.ConvertWith(jsonLines(t, records...), sink, 0, options); err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, row := range sink.Table("works", "works").Rows {
+				ids = append(ids, row[0].(string))
+			}
+			if !slices.Equal(ids, test.ids) {
+				t.Errorf("converted %v, expected %v", ids, test.ids)
+			}
+		})
+	}
+}
+
+func TestOptionsFiltersGoConverter(t *testing.T) {
+	filter, err := ParseFilter("publication_year >= 2010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	options.AddFilter("works", filter)
+
+	// Gets the records that match, and the lines that failed to decode
+	var ids []string
+	failed := 0
+	entityType := EntityType{Name: "works", Convert: func(lines iter.Seq2[JsonLine, error], sink Sink, chunk int) error {
+		for line, err := range lines {
+			if err != nil {
+				failed++
+				continue
+			}
+			ids = append(ids, line.Data["id"].(string))
+		}
+		return nil
+	}}
+
+	lines := func(yield func(JsonLine, error) bool) {
+		for line, err := range jsonLines(t, `{"id": "W1", "publication_year": 2000}`, `{"id": "W2", "publication_year": 2010}`) {
+			if !yield(line, err) {
+				return
+			}
+		}
+		yield(JsonLine{Source: "test", Line: 3}, errors.New("invalid JSON"))
+	}
+	if err := entityType.ConvertWith(lines, NewMemorySink(), 0, options); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []string{"W2"}) || failed != 1 {
+		t.Errorf("converted %v with %v failed lines, expected [W2] with 1", ids, failed)
+	}
+}
@@ -4,16 +4,37 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
-	"encoding/csv"
 	"encoding/json"
+	"io"
 	"iter"
 	"os"
-	"path/filepath"
-
-	"github.com/jszwec/csvutil"
 )
 
-func ReadJsonLines(gzipPath string) (iter.Seq2[map[string]any, error], error) {
+// A line of an input file along with where it came from
+type JsonLine struct {
+	Data map[string]any
+	// Input file path and 1-based line number
+	Source string
+	Line   int
+	// Only valid until the next line is read
+	Raw []byte
+}
+
+// Longest input line the scanners accept. Records with huge titles or abstracts exceed
+// bufio.Scanner's default of 64KB, which would end the file at that line
+const maxLineSize = 256 << 20
+
+// Decodes a single record the way the input files are read, with numbers kept as json.Number
+func DecodeJsonLine(raw []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var data map[string]any
+	err := decoder.Decode(&data)
+	return data, err
+}
+
+func ReadJsonLines(gzipPath string) (iter.Seq2[JsonLine, error], error) {
 	file, err := os.Open(gzipPath)
 	if err != nil {
 		return nil, err
@@ -21,86 +42,61 @@ func ReadJsonLines(gzipPath string) (iter.Seq2[map[string]any, error], error) {
 
 	gzReader, err := gzip.NewReader(file)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(gzReader)
+	lines := JsonLines(gzReader, gzipPath)
 
-	return func(yield func(map[string]any, error) bool) {
+	return func(yield func(JsonLine, error) bool) {
 		defer file.Close()
 		defer gzReader.Close()
 
-		for scanner.Scan() {
-			decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
-			decoder.UseNumber()
+		for line, err := range lines {
+			if !yield(line, err) {
+				return
+			}
+		}
+	}, nil
+}
 
-			var data map[string]any
-			err := decoder.Decode(&data)
+// Reads uncompressed JSON lines from r, naming source as their origin
+func JsonLines(r io.Reader, source string) iter.Seq2[JsonLine, error] {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
 
-			if !yield(data, err) {
+	return func(yield func(JsonLine, error) bool) {
+		lineNumber := 0
+		for scanner.Scan() {
+			lineNumber++
+
+			data, err := DecodeJsonLine(scanner.Bytes())
+			if !yield(JsonLine{Data: data, Source: source, Line: lineNumber, Raw: scanner.Bytes()}, err) {
 				return
 			}
 		}
 		if err := scanner.Err(); err != nil {
-			yield(nil, err)
+			yield(JsonLine{Source: source, Line: lineNumber + 1}, err)
 		}
-	}, nil
+	}
 }
 
-func ReadJsonLinesAll(gzipPaths iter.Seq[string]) iter.Seq2[map[string]any, error] {
-	return func(yield func(map[string]any, error) bool) {
+func ReadJsonLinesAll(gzipPaths iter.Seq[string]) iter.Seq2[JsonLine, error] {
+	return func(yield func(JsonLine, error) bool) {
 		for path := range gzipPaths {
 			jsonLines, err := ReadJsonLines(path)
-			if err != nil && !yield(nil, err) {
-				return
+			if err != nil {
+				if !yield(JsonLine{Source: path}, err) {
+					return
+				}
+				continue
 			}
 
-			jsonLines(yield)
+			for line, err := range jsonLines {
+				if !yield(line, err) {
+					return
+				}
+			}
 		}
 	}
 }
-
-type CsvWriterEncoder struct {
-	file    *os.File
-	archive *gzip.Writer
-	writer  *csv.Writer
-	encoder *csvutil.Encoder
-}
-
-func (csv *CsvWriterEncoder) Close() error {
-	csv.writer.Flush()
-
-	if err := csv.archive.Close(); err != nil {
-		return err
-	}
-	if err := csv.file.Close(); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (csv *CsvWriterEncoder) Encode(v any) error {
-	return csv.encoder.Encode(v)
-}
-
-func OpenCsvEncoder(path string, schema any) (*CsvWriterEncoder, error) {
-	err := os.MkdirAll(filepath.Dir(path), 0755)
-	if err != nil {
-		return nil, err
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
-		return nil, err
-	}
-
-	archive := gzip.NewWriter(file)
-	writer := csv.NewWriter(archive)
-	encoder := csvutil.NewEncoder(writer)
-
-	if err := encoder.EncodeHeader(schema); err != nil {
-		return nil, err
-	}
-
-	return &CsvWriterEncoder{file, archive, writer, encoder}, nil
-}
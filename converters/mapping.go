@@ -0,0 +1,805 @@
+package converters
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Declarative description of how an entity type is turned into tables.
+//
+// Column paths are dot-separated keys relative to the row's context:
+// the record, the object selected with "from" or the array element selected with "explode".
+// A path starting with "$." is relative to the record instead, one starting with "^." to the element
+// enclosing the exploded one, "@" is the array element itself and "#" its index, or the key of a "keys" table.
+// A number in a path indexes an array, like "last_known_institutions.0.id"
+type Mapping struct {
+	Entity string `json:"entity"`
+	// Path that must hold a string, records without it are rejected as missing an id
+	Id     string         `json:"id"`
+	Tables []MappingTable `json:"tables"`
+
+	// Shipped with the tool
+	builtin bool
+}
+
+type MappingTable struct {
+	// Several tables may have the same name and columns, their rows are written to the same table
+	Name string `json:"name"`
+	// Path to an object, giving one row if present
+	From string `json:"from,omitempty"`
+	// Path to an array, giving one row per element. Arrays nested in the elements are exploded
+	// with "[]", like "authorships[].institutions", giving a row per element of the innermost array
+	Explode string `json:"explode,omitempty"`
+	// With explode, write a row with only the columns outside the innermost array filled
+	// when it has no elements that give a row
+	Outer bool `json:"outer,omitempty"`
+	// Paths to objects, giving one row per key of any of them, in sorted order
+	Keys []string `json:"keys,omitempty"`
+	// Written, but not loaded by the import script
+	NoImport bool            `json:"no_import,omitempty"`
+	Columns  []MappingColumn `json:"columns"`
+
+	// Already in openalex-duckdb-schema.sql, so the import script doesn't create it
+	builtin bool
+}
+
+type MappingColumn struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	// Paths read in order when path is null
+	Fallback []string `json:"fallback,omitempty"`
+	// Constant written instead of reading a path
+	Value *string `json:"value,omitempty"`
+	// Joins the strings of the array at path with this separator, null for an empty array
+	Join string `json:"join,omitempty"`
+	// DuckDB type, which also decides what JSON type the value must have
+	Type string `json:"type"`
+	// Skip the row when this column is null
+	Required bool `json:"required,omitempty"`
+	// Superseded by another table, only filled when Options.TopicsLegacyColumns is set
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// JSON type a column of the given SQL type is read as
+func mappingValueKind(sqlType string) (string, bool) {
+	switch strings.ToUpper(sqlType) {
+	case "TEXT", "VARCHAR", "DATE", "TIMESTAMP":
+		return "string", true
+	case "INTEGER", "BIGINT", "SMALLINT", "DOUBLE", "FLOAT", "REAL", "DECIMAL":
+		return "number", true
+	case "BOOLEAN":
+		return "boolean", true
+	case "JSON":
+		return "json", true
+	default:
+		return "", false
+	}
+}
+
+func (table *MappingTable) explodeLevels() []string {
+	return strings.Split(table.Explode, "[].")
+}
+
+func (table *MappingTable) validatePath(column *MappingColumn, path string) error {
+	switch {
+	case path == "":
+		return fmt.Errorf("table %v column %v has an empty path", table.Name, column.Name)
+	case path == "@" && table.Explode == "":
+		return fmt.Errorf("table %v column %v uses @ outside of explode", table.Name, column.Name)
+	case path == "#" && table.Explode == "" && len(table.Keys) == 0:
+		return fmt.Errorf("table %v column %v uses # outside of explode or keys", table.Name, column.Name)
+	case strings.HasPrefix(path, "^.") && table.Explode == "":
+		return fmt.Errorf("table %v column %v uses ^. outside of explode", table.Name, column.Name)
+	case path != "#" && slices.Contains(splitPath(path), "#") && len(table.Keys) == 0:
+		return fmt.Errorf("table %v column %v uses # in a path outside of keys", table.Name, column.Name)
+	}
+	return nil
+}
+
+func (m *Mapping) validate() error {
+	if m.Entity == "" {
+		return errors.New("mapping has no entity")
+	}
+	if len(m.Tables) == 0 {
+		return fmt.Errorf("mapping %v has no tables", m.Entity)
+	}
+
+	tables := map[string]*MappingTable{}
+	for i := range m.Tables {
+		table := &m.Tables[i]
+		if table.Name == "" {
+			return fmt.Errorf("mapping %v has a table without a name", m.Entity)
+		}
+		if len(table.Columns) == 0 {
+			return fmt.Errorf("table %v has no columns", table.Name)
+		}
+
+		sources := 0
+		for _, source := range []bool{table.From != "", table.Explode != "", len(table.Keys) > 0} {
+			if source {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return fmt.Errorf("table %v can only have one of from, explode and keys", table.Name)
+		}
+		if table.Outer && table.Explode == "" {
+			return fmt.Errorf("table %v is outer without explode", table.Name)
+		}
+
+		for j := range table.Columns {
+			column := &table.Columns[j]
+			if column.Name == "" {
+				return fmt.Errorf("table %v has a column without a name", table.Name)
+			}
+			if _, ok := mappingValueKind(column.Type); !ok {
+				return fmt.Errorf("table %v column %v has unsupported type %q", table.Name, column.Name, column.Type)
+			}
+
+			if column.Value != nil {
+				if column.Path != "" || column.Fallback != nil || column.Join != "" {
+					return fmt.Errorf("table %v column %v has a value and a path", table.Name, column.Name)
+				}
+				if kind, _ := mappingValueKind(column.Type); kind != "string" {
+					return fmt.Errorf("table %v column %v has a value but isn't text", table.Name, column.Name)
+				}
+				continue
+			}
+			if column.Join != "" {
+				if kind, _ := mappingValueKind(column.Type); kind != "string" {
+					return fmt.Errorf("table %v column %v is joined but isn't text", table.Name, column.Name)
+				}
+			}
+
+			for _, path := range append([]string{column.Path}, column.Fallback...) {
+				if err := table.validatePath(column, path); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Tables with the same name are written to the same files
+		if first, exists := tables[table.Name]; exists {
+			if !slices.EqualFunc(first.tableSchema().Columns, table.tableSchema().Columns, func(a, b Column) bool { return a == b }) ||
+				first.NoImport != table.NoImport {
+				return fmt.Errorf("mapping %v has table %v twice with different columns", m.Entity, table.Name)
+			}
+		}
+		tables[table.Name] = table
+	}
+	return nil
+}
+
+func parseMapping(r io.Reader) (*Mapping, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var mapping Mapping
+	if err := decoder.Decode(&mapping); err != nil {
+		return nil, err
+	}
+	if err := mapping.validate(); err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+func LoadMapping(path string) (*Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mapping, err := parseMapping(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return mapping, nil
+}
+
+//go:embed mappings/*.json
+var builtinMappings embed.FS
+
+func mustLoadBuiltinMapping(entity string) *Mapping {
+	f, err := builtinMappings.Open("mappings/" + entity + ".json")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	mapping, err := parseMapping(f)
+	if err != nil {
+		panic(fmt.Errorf("mappings/%v.json: %w", entity, err))
+	}
+	mapping.builtin = true
+	for i := range mapping.Tables {
+		mapping.Tables[i].builtin = true
+	}
+	return mapping
+}
+
+// A mapping with the tables of both, for extending a built-in mapping
+func (m *Mapping) extend(other *Mapping) (*Mapping, error) {
+	extended := *m
+	extended.Tables = append(slices.Clone(m.Tables), other.Tables...)
+	if err := extended.validate(); err != nil {
+		return nil, err
+	}
+	return &extended, nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// Index of a path key that indexes an array
+func pathIndex(key string) (int, bool) {
+	index, err := strconv.Atoi(key)
+	return index, err == nil && index >= 0
+}
+
+type mappingLookupKey struct {
+	// Object or array the key is read from
+	container uintptr
+	key       string
+	// SQL type of a value, or "object" or "array" for containers
+	sqlType string
+}
+
+type mappingValue struct {
+	value   any
+	present bool
+	cast    *mappingCast
+}
+
+type mappingChild struct {
+	container any
+	cast      *mappingCast
+}
+
+// Outcome of reading a field, counted by the cast statistics for every column that reads it
+type mappingCast struct {
+	path     string
+	expected string
+	value    any
+	exists   bool
+	ok       bool
+}
+
+// Per-record cache, so that a field shared by several columns or rows
+// is read (and seen by the field audit) only once
+type mappingReader struct {
+	options    *Options
+	containers map[mappingLookupKey]mappingChild
+	values     map[mappingLookupKey]mappingValue
+	// JSON path of every object and array read so far, only kept while paths are tracked
+	paths map[uintptr]string
+	// Table and column being written, which the cast statistics count reads for
+	table  string
+	column string
+	// Reads of the column path being tried, only kept while columnValue tries its paths
+	pending   []*mappingCast
+	deferring bool
+}
+
+func newMappingReader(options *Options, record map[string]any) *mappingReader {
+	r := &mappingReader{
+		options:    options,
+		containers: map[mappingLookupKey]mappingChild{},
+		values:     map[mappingLookupKey]mappingValue{},
+	}
+	if options.tracksPaths() {
+		r.paths = map[uintptr]string{mapKey(record): ""}
+	}
+	return r
+}
+
+func containerKey(container any) uintptr {
+	switch c := container.(type) {
+	case map[string]any:
+		return mapKey(c)
+	case []any:
+		return sliceKey(c)
+	}
+	return 0
+}
+
+// JSON path of key in container, like "authorships[].author.id"
+func (r *mappingReader) pathOf(container any, key string) string {
+	if _, isArray := container.([]any); isArray {
+		return r.paths[containerKey(container)] + "[]"
+	}
+	return joinPath(r.paths[containerKey(container)], key)
+}
+
+// Reads key of an object, or an index of an array, as T.
+// Returns what the cast statistics count for it: every field read, array elements only if they have another type
+func readAt[T any](r *mappingReader, container any, key string) (*T, *mappingCast) {
+	var value any
+	var exists bool
+	switch c := container.(type) {
+	case map[string]any:
+		value, exists = c[key]
+	case []any:
+		index, _ := pathIndex(key)
+		if index >= len(c) {
+			return nil, nil
+		}
+		value, exists = c[index], true
+	}
+
+	cast, ok := value.(T)
+	var counted *mappingCast
+	if r.paths != nil {
+		path := r.pathOf(container, key)
+		_, isArray := container.([]any)
+		if !isArray {
+			r.options.FieldAudit.consume(path, false)
+		}
+		if r.options.CastStats != nil && (!isArray || !ok) {
+			counted = &mappingCast{path: path, expected: expectedTypeName[T](), value: value, exists: exists, ok: ok}
+		}
+	}
+	if !ok {
+		return nil, counted
+	}
+	return &cast, counted
+}
+
+// Counts a read for the current table and column, cached reads included
+func (r *mappingReader) countCast(cast *mappingCast) {
+	if cast != nil && r.deferring {
+		r.pending = append(r.pending, cast)
+	} else if cast != nil {
+		key := castKey{table: r.table, column: r.column, path: cast.path, expected: cast.expected}
+		r.options.CastStats.recordValue(key, cast.value, cast.exists, cast.ok)
+	}
+}
+
+// Reads the object, or with array the array, at key of parent. Nil if absent or of another type
+func (r *mappingReader) child(parent any, key string, array bool) any {
+	lookup := mappingLookupKey{container: containerKey(parent), key: key, sqlType: "object"}
+	if array {
+		lookup.sqlType = "array"
+	}
+	if cached, ok := r.containers[lookup]; ok {
+		r.countCast(cached.cast)
+		return cached.container
+	}
+
+	var child mappingChild
+	if array {
+		var arr *[]any
+		if arr, child.cast = readAt[[]any](r, parent, key); arr != nil {
+			child.container = *arr
+		}
+	} else {
+		var object *map[string]any
+		if object, child.cast = readAt[map[string]any](r, parent, key); object != nil {
+			child.container = *object
+		}
+	}
+	r.containers[lookup] = child
+	r.countCast(child.cast)
+
+	if r.paths != nil && child.container != nil {
+		r.paths[containerKey(child.container)] = r.pathOf(parent, key)
+	}
+	return child.container
+}
+
+// Object, or with array an array, at keys below m. Nil if absent or of another type
+func (r *mappingReader) container(m map[string]any, keys []string, array bool) any {
+	if len(keys) == 0 {
+		return m
+	}
+
+	last := keys[len(keys)-1]
+	_, indexed := pathIndex(last)
+	parent := r.container(m, keys[:len(keys)-1], indexed)
+	if parent == nil {
+		return nil
+	}
+	return r.child(parent, last, array)
+}
+
+func (r *mappingReader) object(m map[string]any, keys []string) map[string]any {
+	object, _ := r.container(m, keys, false).(map[string]any)
+	return object
+}
+
+func (r *mappingReader) array(m map[string]any, keys []string) []any {
+	arr, _ := r.container(m, keys, true).([]any)
+	return arr
+}
+
+// Reads the value at keys as the column's type
+func (r *mappingReader) value(m map[string]any, keys []string, sqlType string) (any, bool) {
+	last := keys[len(keys)-1]
+	_, indexed := pathIndex(last)
+	return r.read(r.container(m, keys[:len(keys)-1], indexed), last, sqlType)
+}
+
+// Reads key of an object, or an index of an array, as the column's type
+func (r *mappingReader) read(container any, key string, sqlType string) (any, bool) {
+	if container == nil {
+		return nil, false
+	}
+
+	lookup := mappingLookupKey{container: containerKey(container), key: key, sqlType: sqlType}
+	value, cached := r.values[lookup]
+	if !cached {
+		value = r.readValue(container, key, sqlType)
+		r.values[lookup] = value
+	}
+	r.countCast(value.cast)
+	return value.value, value.present
+}
+
+func (r *mappingReader) readValue(container any, key string, sqlType string) mappingValue {
+	kind, _ := mappingValueKind(sqlType)
+
+	switch kind {
+	case "string":
+		value, cast := readAt[string](r, container, key)
+		if value != nil {
+			return mappingValue{*value, true, cast}
+		}
+		return mappingValue{cast: cast}
+	case "number":
+		value, cast := readAt[json.Number](r, container, key)
+		if value != nil {
+			return mappingValue{*value, true, cast}
+		}
+		return mappingValue{cast: cast}
+	case "boolean":
+		value, cast := readAt[bool](r, container, key)
+		if value != nil {
+			return mappingValue{*value, true, cast}
+		}
+		return mappingValue{cast: cast}
+	case "json":
+		var value jsontype
+		switch c := container.(type) {
+		case map[string]any:
+			value = jsontype{c[key]}
+			if r.paths != nil {
+				r.options.FieldAudit.consume(r.pathOf(container, key), true)
+			}
+		case []any:
+			if index, _ := pathIndex(key); index < len(c) {
+				value = jsontype{c[index]}
+			}
+		}
+		if raw := value.rawJson(); raw != nil {
+			return mappingValue{value: raw, present: true}
+		}
+	}
+	return mappingValue{}
+}
+
+// What the paths of a row's columns are relative to
+type mappingContext struct {
+	record map[string]any
+	// The from object, array element or record, nil for elements that aren't objects
+	object map[string]any
+	// Element enclosing the exploded one, or the record
+	parent map[string]any
+	// Exploded array, whose element at index is "@"
+	arr []any
+	// Index of the element or key of a keys table, for "#"
+	index any
+	// Outer row of an exploded table, without an element
+	outer bool
+}
+
+// Whether path reads from the exploded element, which an outer row doesn't have
+func isElementPath(path string) bool {
+	return !strings.HasPrefix(path, "$.") && !strings.HasPrefix(path, "^.")
+}
+
+func (r *mappingReader) pathValue(column *MappingColumn, path string, ctx *mappingContext) (any, bool) {
+	switch path {
+	case "@":
+		index, ok := ctx.index.(int)
+		if !ok {
+			return nil, false
+		}
+		return r.read(ctx.arr, strconv.Itoa(index), column.Type)
+	case "#":
+		return ctx.index, ctx.index != nil
+	}
+
+	base := ctx.object
+	if rest, found := strings.CutPrefix(path, "$."); found {
+		base, path = ctx.record, rest
+	} else if rest, found := strings.CutPrefix(path, "^."); found {
+		base, path = ctx.parent, rest
+	}
+	if base == nil {
+		return nil, false
+	}
+
+	keys := splitPath(path)
+	if key, ok := ctx.index.(string); ok {
+		for i := range keys {
+			if keys[i] == "#" {
+				keys[i] = key
+			}
+		}
+	}
+
+	if column.Join != "" {
+		arr := r.array(base, keys)
+		var parts []string
+		for i := range arr {
+			if part, ok := r.read(arr, strconv.Itoa(i), "TEXT"); ok {
+				parts = append(parts, part.(string))
+			}
+		}
+		if len(parts) == 0 {
+			return nil, false
+		}
+		return strings.Join(parts, column.Join), true
+	}
+	return r.value(base, keys, column.Type)
+}
+
+func (r *mappingReader) columnValue(column *MappingColumn, ctx *mappingContext) (any, bool) {
+	if column.Value != nil {
+		return *column.Value, true
+	}
+	if column.Legacy && !r.options.TopicsLegacyColumns {
+		return nil, false
+	}
+
+	// Only the path that supplied the value, or the last one tried, is counted,
+	// so a fallback filling the column doesn't count the paths before it as failed
+	r.deferring = len(column.Fallback) > 0 && r.options.CastStats != nil
+	defer r.countPending()
+
+	for _, path := range append([]string{column.Path}, column.Fallback...) {
+		if ctx.outer && isElementPath(path) {
+			continue
+		}
+		r.pending = r.pending[:0]
+		if value, present := r.pathValue(column, path, ctx); present {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (r *mappingReader) countPending() {
+	if !r.deferring {
+		return
+	}
+	r.deferring = false
+	for _, cast := range r.pending {
+		r.countCast(cast)
+	}
+	r.pending = r.pending[:0]
+}
+
+// Open tables of a mapping for one chunk
+type mappingWriters struct {
+	mapping *Mapping
+	options *Options
+	// Writer of each table of the mapping, shared by tables with the same name
+	writers []RowWriter
+	// Columns of each table the sink writes, nil for tables it doesn't write
+	selected [][]bool
+}
+
+func openMapping(mapping *Mapping, sink Sink, chunk int, options *Options) (*mappingWriters, error) {
+	mw := &mappingWriters{mapping: mapping, options: options}
+
+	opened := map[string]RowWriter{}
+	for _, table := range mapping.Tables {
+		writer, exists := opened[table.Name]
+		if !exists {
+			var err error
+			writer, err = sink.OpenTable(mapping.Entity, table.tableSchema(), chunk)
+			if err != nil {
+				mw.Close()
+				return nil, err
+			}
+			opened[table.Name] = writer
+		}
+		mw.writers = append(mw.writers, writer)
+		mw.selected = append(mw.selected, selectedColumns(sink, mapping.Entity, table.tableSchema()))
+	}
+	return mw, nil
+}
+
+func (mw *mappingWriters) Close() {
+	closed := map[RowWriter]struct{}{}
+	for _, writer := range mw.writers {
+		if _, exists := closed[writer]; exists {
+			continue
+		}
+		closed[writer] = struct{}{}
+
+		if err := writer.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Writes a row of the table at index unless a required column is null, returning whether it was written.
+// Columns the sink doesn't write are left null without reading them
+func (mw *mappingWriters) writeRow(r *mappingReader, index int, ctx *mappingContext) bool {
+	table := &mw.mapping.Tables[index]
+	selected := mw.selected[index]
+	row := make(Row, len(table.Columns))
+	// Reads finding the next row belong to the table again
+	defer func() { r.column = "" }()
+
+	for i := range table.Columns {
+		column := &table.Columns[i]
+		if !selected[i] && !column.Required {
+			continue
+		}
+		r.column = column.Name
+		value, present := r.columnValue(column, ctx)
+
+		// Columns of the missing element are null in an outer row
+		if !present && column.Required && !(ctx.outer && isElementPath(column.Path)) {
+			return false
+		}
+		row[i] = value
+	}
+
+	if err := mw.writers[index].WriteRow(row); err != nil {
+		log.Println(err)
+	}
+	return true
+}
+
+// Whether a table reads anything relative to its array elements other than the elements themselves
+func (table *MappingTable) readsElementFields() bool {
+	for _, column := range table.Columns {
+		if column.Value != nil {
+			continue
+		}
+		for _, path := range append([]string{column.Path}, column.Fallback...) {
+			if path != "@" && path != "#" && isElementPath(path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Writes the rows of the table at index for the array at levels[0] below ctx.object,
+// exploding the remaining levels of each element. Returns the number of rows written
+func (mw *mappingWriters) explode(r *mappingReader, index int, ctx *mappingContext, levels []string) int {
+	table := &mw.mapping.Tables[index]
+	innermost := len(levels) == 1
+	arr := r.array(ctx.object, splitPath(levels[0]))
+
+	written := 0
+	needsObject := !innermost || table.readsElementFields()
+	for i, element := range arr {
+		object, _ := element.(map[string]any)
+		if needsObject {
+			if object, _ = r.child(arr, strconv.Itoa(i), false).(map[string]any); object == nil {
+				continue
+			}
+		}
+
+		elementCtx := &mappingContext{record: ctx.record, object: object, parent: ctx.object, arr: arr, index: i}
+		if innermost {
+			if mw.writeRow(r, index, elementCtx) {
+				written++
+			}
+		} else {
+			written += mw.explode(r, index, elementCtx, levels[1:])
+		}
+	}
+
+	if innermost && written == 0 && table.Outer {
+		if mw.writeRow(r, index, &mappingContext{record: ctx.record, parent: ctx.object, outer: true}) {
+			written++
+		}
+	}
+	return written
+}
+
+func (mw *mappingWriters) convert(data map[string]any) error {
+	r := newMappingReader(mw.options, data)
+	if mw.mapping.Id != "" {
+		if _, present := r.value(data, splitPath(mw.mapping.Id), "TEXT"); !present {
+			return errMissingId
+		}
+	}
+
+	for i := range mw.mapping.Tables {
+		table := &mw.mapping.Tables[i]
+		if mw.selected[i] == nil {
+			continue
+		}
+		r.table = table.Name
+		recordCtx := &mappingContext{record: data, object: data, parent: data}
+
+		switch {
+		case table.Explode != "":
+			mw.explode(r, i, recordCtx, table.explodeLevels())
+		case table.From != "":
+			if object := r.object(data, splitPath(table.From)); object != nil {
+				mw.writeRow(r, i, &mappingContext{record: data, object: object, parent: data})
+			}
+		case len(table.Keys) > 0:
+			keys := map[string]struct{}{}
+			for _, path := range table.Keys {
+				for key := range r.object(data, splitPath(path)) {
+					keys[key] = struct{}{}
+				}
+			}
+			for _, key := range slices.Sorted(maps.Keys(keys)) {
+				mw.writeRow(r, i, &mappingContext{record: data, object: data, parent: data, index: key})
+			}
+		default:
+			mw.writeRow(r, i, recordCtx)
+		}
+	}
+	return nil
+}
+
+func (table *MappingTable) tableSchema() TableSchema {
+	columns := make([]Column, len(table.Columns))
+	for i, column := range table.Columns {
+		columns[i] = Column{Name: column.Name, Type: strings.ToUpper(column.Type)}
+	}
+	return TableSchema{Name: table.Name, Columns: columns, CreateTable: !table.builtin}
+}
+
+// Tables loaded by the import script, each once
+func (mapping *Mapping) tableSchemas() []TableSchema {
+	var tables []TableSchema
+	for _, table := range mapping.Tables {
+		if table.NoImport || slices.ContainsFunc(tables, func(t TableSchema) bool { return t.Name == table.Name }) {
+			continue
+		}
+		tables = append(tables, table.tableSchema())
+	}
+	return tables
+}
+
+func convertMapping(mapping *Mapping, lines iter.Seq2[JsonLine, error], sink Sink, chunk int, options *Options) error {
+	if options == nil {
+		options = NewOptions()
+	}
+
+	buffer := newRecordBuffer(sink)
+	mw, err := openMapping(mapping, buffer, chunk, options)
+	if err != nil {
+		return err
+	}
+	defer mw.Close()
+
+	return forEachRecord(lines, buffer, mapping.Entity, chunk, options, mw.convert)
+}
+
+// Builds an entity type whose conversion is driven entirely by a mapping
+func mappingEntityType(mapping *Mapping) EntityType {
+	return EntityType{
+		Name:   mapping.Entity,
+		Tables: mapping.tableSchemas(),
+		Convert: func(lines iter.Seq2[JsonLine, error], sink Sink, chunk int) error {
+			return convertMapping(mapping, lines, sink, chunk, NewOptions())
+		},
+		mapping: mapping,
+	}
+}
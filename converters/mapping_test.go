@@ -0,0 +1,531 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Values of a row as text, NULL for nil
+func formatRow(row Row) string {
+	values := make([]string, len(row))
+	for i, value := range row {
+		switch value := value.(type) {
+		case nil:
+			values[i] = "NULL"
+		case json.RawMessage:
+			values[i] = string(value)
+		default:
+			values[i] = fmt.Sprint(value)
+		}
+	}
+	return strings.Join(values, "|")
+}
+
+// Rows of every table written to sink, formatted with formatRow
+func formatTables(sink *MemorySink) map[string][]string {
+	tables := map[string][]string{}
+	for key, table := range sink.tables {
+		for _, row := range table.Rows {
+			tables[key.table] = append(tables[key.table], formatRow(row))
+		}
+	}
+	return tables
+}
+
+func mustParseMapping(t *testing.T, spec string) *Mapping {
+	t.Helper()
+
+	mapping, err := parseMapping(strings.NewReader(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mapping
+}
+
+func TestParseMappingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{name: "unknown field", spec: `{"entity": "things", "tables": [], "extra": 1}`, err: `unknown field "extra"`},
+		{name: "no entity", spec: `{"tables": [{"name": "t", "columns": [{"name": "c", "path": "c", "type": "TEXT"}]}]}`, err: "mapping has no entity"},
+		{name: "no tables", spec: `{"entity": "things", "tables": []}`, err: "mapping things has no tables"},
+		{name: "table without name", spec: `{"entity": "things", "tables": [{"columns": [{"name": "c", "path": "c", "type": "TEXT"}]}]}`, err: "table without a name"},
+		{name: "no columns", spec: `{"entity": "things", "tables": [{"name": "t", "columns": []}]}`, err: "table t has no columns"},
+		{
+			name: "several sources",
+			spec: `{"entity": "things", "tables": [{"name": "t", "from": "a", "explode": "b", "columns": [{"name": "c", "path": "c", "type": "TEXT"}]}]}`,
+			err:  "only have one of from, explode and keys",
+		},
+		{
+			name: "outer without explode",
+			spec: `{"entity": "things", "tables": [{"name": "t", "outer": true, "columns": [{"name": "c", "path": "c", "type": "TEXT"}]}]}`,
+			err:  "table t is outer without explode",
+		},
+		{name: "column without name", spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"path": "c", "type": "TEXT"}]}]}`, err: "column without a name"},
+		{name: "unsupported type", spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "c", "type": "BLOB"}]}]}`, err: `unsupported type "BLOB"`},
+		{name: "empty path", spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "type": "TEXT"}]}]}`, err: "column c has an empty path"},
+		{
+			name: "empty fallback",
+			spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "c", "fallback": [""], "type": "TEXT"}]}]}`,
+			err:  "column c has an empty path",
+		},
+		{
+			name: "value and path",
+			spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "c", "value": "v", "type": "TEXT"}]}]}`,
+			err:  "column c has a value and a path",
+		},
+		{
+			name: "value that isn't text",
+			spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "value": "1", "type": "INTEGER"}]}]}`,
+			err:  "column c has a value but isn't text",
+		},
+		{
+			name: "join that isn't text",
+			spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "c", "join": ",", "type": "INTEGER"}]}]}`,
+			err:  "column c is joined but isn't text",
+		},
+		{name: "@ outside explode", spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "@", "type": "TEXT"}]}]}`, err: "uses @ outside of explode"},
+		{name: "# outside explode", spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "#", "type": "TEXT"}]}]}`, err: "uses # outside of explode or keys"},
+		{name: "^. outside explode", spec: `{"entity": "things", "tables": [{"name": "t", "columns": [{"name": "c", "path": "^.a", "type": "TEXT"}]}]}`, err: "uses ^. outside of explode"},
+		{
+			name: "# in a path outside keys",
+			spec: `{"entity": "things", "tables": [{"name": "t", "explode": "a", "columns": [{"name": "c", "path": "b.#", "type": "TEXT"}]}]}`,
+			err:  "uses # in a path outside of keys",
+		},
+		{
+			name: "same name with other columns",
+			spec: `{"entity": "things", "tables": [
+				{"name": "t", "from": "a", "columns": [{"name": "c", "path": "c", "type": "TEXT"}]},
+				{"name": "t", "from": "b", "columns": [{"name": "c", "path": "c", "type": "INTEGER"}]}
+			]}`,
+			err: "mapping things has table t twice with different columns",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseMapping(strings.NewReader(test.spec))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %v, expected it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestConvertMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		records []string
+		options *Options
+		tables  map[string][]string
+		// Records rejected to the dead letters
+		rejected int
+	}{
+		{
+			name: "columns",
+			spec: `{"entity": "things", "id": "id", "tables": [{"name": "things", "columns": [
+				{"name": "id", "path": "id", "type": "TEXT"},
+				{"name": "count", "path": "count", "type": "INTEGER"},
+				{"name": "ok", "path": "ok", "type": "BOOLEAN"},
+				{"name": "extra", "path": "extra", "type": "JSON"},
+				{"name": "title", "path": "title", "fallback": ["display_name"], "type": "TEXT"},
+				{"name": "kind", "value": "thing", "type": "TEXT"},
+				{"name": "tags", "path": "tags", "join": ";", "type": "TEXT"},
+				{"name": "first_tag", "path": "tags.0", "type": "TEXT"},
+				{"name": "deep", "path": "a.b.c", "type": "TEXT"}
+			]}]}`,
+			records: []string{
+				`{"id": "T1", "count": 3, "ok": true, "extra": {"x": [1]}, "display_name": "D", "tags": ["a", 1, "b"], "a": {"b": {"c": "deep"}}}`,
+				`{"id": "T2", "count": "3", "ok": null, "title": "Title", "display_name": "D", "tags": [], "a": {"b": "c"}}`,
+				`{"count": 1}`,
+				`{"id": 3}`,
+			},
+			tables: map[string][]string{"things": {
+				`T1|3|true|{"x":[1]}|D|thing|a;b|a|deep`,
+				"T2|NULL|NULL|NULL|Title|thing|NULL|NULL|NULL",
+			}},
+			rejected: 2,
+		},
+		{
+			name: "explode",
+			spec: `{"entity": "works", "id": "id", "tables": [
+				{"name": "authorships", "explode": "authorships", "columns": [
+					{"name": "work_id", "path": "$.id", "type": "TEXT"},
+					{"name": "position", "path": "#", "type": "INTEGER"},
+					{"name": "author_id", "path": "author.id", "type": "TEXT"}
+				]},
+				{"name": "institutions", "explode": "authorships[].institutions", "outer": true, "columns": [
+					{"name": "work_id", "path": "$.id", "type": "TEXT"},
+					{"name": "author_id", "path": "^.author.id", "type": "TEXT"},
+					{"name": "institution_id", "path": "id", "type": "TEXT", "required": true}
+				]},
+				{"name": "tags", "explode": "tags", "columns": [
+					{"name": "work_id", "path": "$.id", "type": "TEXT"},
+					{"name": "tag", "path": "@", "type": "TEXT", "required": true},
+					{"name": "position", "path": "#", "type": "INTEGER"}
+				]}
+			]}`,
+			records: []string{
+				`{"id": "W1", "authorships": [
+					{"author": {"id": "A1"}, "institutions": [{"id": "I1"}, {"name": "no id"}, {"id": "I2"}]},
+					{"author": {"id": "A2"}, "institutions": []},
+					"not an object",
+					{"author": {"id": "A3"}}
+				], "tags": ["x", 5, "y"]}`,
+				`{"id": "W2", "authorships": {"author": {"id": "A1"}}, "tags": null}`,
+			},
+			tables: map[string][]string{
+				"authorships":  {"W1|0|A1", "W1|1|A2", "W1|3|A3"},
+				"institutions": {"W1|A1|I1", "W1|A1|I2", "W1|A2|NULL", "W1|A3|NULL"},
+				"tags":         {"W1|x|0", "W1|y|2"},
+			},
+		},
+		{
+			name: "nested explode without outer",
+			spec: `{"entity": "works", "tables": [
+				{"name": "institutions", "explode": "authorships[].institutions", "columns": [
+					{"name": "author_position", "path": "^.position", "type": "TEXT"},
+					{"name": "position", "path": "#", "type": "INTEGER"},
+					{"name": "institution", "path": "@", "type": "TEXT"}
+				]}
+			]}`,
+			records: []string{
+				`{"authorships": [{"position": "first", "institutions": ["I1", "I2"]}, {"position": "last", "institutions": []}]}`,
+			},
+			tables: map[string][]string{"institutions": {"first|0|I1", "first|1|I2"}},
+		},
+		{
+			name: "from",
+			spec: `{"entity": "things", "id": "id", "tables": [{"name": "ids", "from": "ids", "columns": [
+				{"name": "id", "path": "$.id", "type": "TEXT"},
+				{"name": "mag", "path": "mag", "type": "BIGINT"}
+			]}]}`,
+			records: []string{
+				`{"id": "T1", "ids": {"mag": 12}}`,
+				`{"id": "T2"}`,
+				`{"id": "T3", "ids": "not an object"}`,
+				`{"id": "T4", "ids": {}}`,
+			},
+			tables: map[string][]string{"ids": {"T1|12", "T4|NULL"}},
+		},
+		{
+			name: "keys",
+			spec: `{"entity": "things", "tables": [{"name": "counts", "keys": ["a", "b"], "columns": [
+				{"name": "id", "path": "id", "type": "TEXT"},
+				{"name": "key", "path": "#", "type": "TEXT"},
+				{"name": "a", "path": "a.#", "type": "INTEGER"},
+				{"name": "b", "path": "b.#", "type": "INTEGER"}
+			]}]}`,
+			records: []string{`{"id": "K1", "a": {"y": 2, "x": 1}, "b": {"z": 4, "y": 3}}`},
+			tables:  map[string][]string{"counts": {"K1|x|1|NULL", "K1|y|2|3", "K1|z|NULL|4"}},
+		},
+		{
+			name: "tables with the same name",
+			spec: `{"entity": "things", "tables": [
+				{"name": "prices", "from": "list", "columns": [
+					{"name": "kind", "value": "list", "type": "TEXT"},
+					{"name": "value", "path": "value", "type": "INTEGER"}
+				]},
+				{"name": "prices", "from": "paid", "columns": [
+					{"name": "kind", "value": "paid", "type": "TEXT"},
+					{"name": "value", "path": "value", "type": "INTEGER"}
+				]}
+			]}`,
+			records: []string{`{"list": {"value": 10}, "paid": {"value": 8}}`, `{"paid": {"value": 5}}`},
+			tables:  map[string][]string{"prices": {"list|10", "paid|8", "paid|5"}},
+		},
+		{
+			name: "legacy columns",
+			spec: `{"entity": "things", "tables": [{"name": "things", "columns": [
+				{"name": "id", "path": "id", "type": "TEXT"},
+				{"name": "old", "path": "old", "type": "TEXT", "legacy": true}
+			]}]}`,
+			records: []string{`{"id": "T1", "old": "o"}`},
+			options: &Options{},
+			tables:  map[string][]string{"things": {"T1|NULL"}},
+		},
+		{
+			name: "legacy columns filled",
+			spec: `{"entity": "things", "tables": [{"name": "things", "columns": [
+				{"name": "id", "path": "id", "type": "TEXT"},
+				{"name": "old", "path": "old", "type": "TEXT", "legacy": true}
+			]}]}`,
+			records: []string{`{"id": "T1", "old": "o"}`},
+			tables:  map[string][]string{"things": {"T1|o"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapping := mustParseMapping(t, test.spec)
+			sink := NewMemorySink()
+			if err := convertMapping(mapping, jsonLines(t, test.records...), sink, 0, test.options); err != nil {
+				t.Fatal(err)
+			}
+
+			tables := formatTables(sink)
+			for name, rows := range test.tables {
+				if !slices.Equal(tables[name], rows) {
+					t.Errorf("%v:\n got %q\nwant %q", name, tables[name], rows)
+				}
+			}
+			for name, rows := range tables {
+				if _, expected := test.tables[name]; !expected && len(rows) > 0 {
+					t.Errorf("unexpected rows in %v: %q", name, rows)
+				}
+			}
+			if rejected := len(sink.DeadLetters()); rejected != test.rejected {
+				t.Errorf("%v records rejected, expected %v", rejected, test.rejected)
+			}
+		})
+	}
+}
+
+func TestBuiltinMappings(t *testing.T) {
+	for _, entityType := range EntityTypes {
+		t.Run(entityType.Name, func(t *testing.T) {
+			if entityType.mapping == nil {
+				t.Skip("not converted by a mapping")
+			}
+
+			// A record with nothing but an id gives a row in the table named after the entity type
+			// and none in the others, which all need something more
+			sink := NewMemorySink()
+			if err := entityType.ConvertWith(jsonLines(t, `{"id": "X1"}`), sink, 0, nil); err != nil {
+				t.Fatal(err)
+			}
+			for _, table := range entityType.Tables {
+				written := sink.Table(entityType.Name, table.Name)
+				if written == nil {
+					t.Errorf("table %v was never opened", table.Name)
+					continue
+				}
+
+				var rows []string
+				for _, row := range written.Rows {
+					rows = append(rows, formatRow(row))
+				}
+				if table.Name == entityType.Name {
+					if len(rows) != 1 || !strings.HasPrefix(rows[0], "X1|") {
+						t.Errorf("%v: %q, expected one row for X1", table.Name, rows)
+					}
+				} else if len(rows) > 0 {
+					t.Errorf("%v: unexpected rows %q", table.Name, rows)
+				}
+			}
+		})
+	}
+}
+
+func TestMappingProjection(t *testing.T) {
+	spec := `{"entity": "things", "tables": [
+		{"name": "things", "columns": [
+			{"name": "id", "path": "id", "type": "TEXT", "required": true},
+			{"name": "title", "path": "title", "type": "TEXT"},
+			{"name": "abstract", "path": "abstract", "type": "JSON"}
+		]},
+		{"name": "things_refs", "explode": "refs", "columns": [
+			{"name": "id", "path": "$.id", "type": "TEXT"},
+			{"name": "ref", "path": "@", "type": "TEXT"}
+		]}
+	]}`
+	records := []string{`{"id": "T1", "title": "Title", "abstract": {"a": [0]}, "refs": ["R1"]}`, `{"title": "no id"}`}
+
+	tests := []struct {
+		name    string
+		tables  []string
+		columns []string
+		rows    map[string][]string
+		// Paths of the input that were never read
+		unmapped []string
+	}{
+		{
+			name:     "everything",
+			rows:     map[string][]string{"things": {"T1|Title|{\"a\":[0]}"}, "things_refs": {"T1|R1"}},
+			unmapped: []string{},
+		},
+		{
+			name:     "one table",
+			tables:   []string{"things.things"},
+			rows:     map[string][]string{"things": {"T1|Title|{\"a\":[0]}"}},
+			unmapped: []string{"refs", "refs[]"},
+		},
+		{
+			name:     "skipped columns",
+			columns:  []string{"things.things:-abstract,-title"},
+			rows:     map[string][]string{"things": {"T1"}, "things_refs": {"T1|R1"}},
+			unmapped: []string{"abstract", "abstract.a", "abstract.a[]", "title"},
+		},
+		{
+			// The required id still decides which rows are written
+			name:     "required column left out",
+			tables:   []string{"things.things"},
+			columns:  []string{"things.things:title"},
+			rows:     map[string][]string{"things": {"Title"}},
+			unmapped: []string{"abstract", "abstract.a", "abstract.a[]", "refs", "refs[]"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mapping := mustParseMapping(t, spec)
+			projection := NewProjection()
+			for _, tables := range test.tables {
+				if err := projection.SelectTables(tables); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, columns := range test.columns {
+				if err := projection.SelectColumns(columns); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			options := NewOptions()
+			options.FieldAudit = NewFieldAudit()
+			sink := NewMemorySink()
+			if err := convertMapping(mapping, jsonLines(t, records...), projection.Sink(sink), 0, options); err != nil {
+				t.Fatal(err)
+			}
+
+			tables := formatTables(sink)
+			for name, rows := range test.rows {
+				if !slices.Equal(tables[name], rows) {
+					t.Errorf("%v:\n got %q\nwant %q", name, tables[name], rows)
+				}
+			}
+			for name, rows := range tables {
+				if _, expected := test.rows[name]; !expected && len(rows) > 0 {
+					t.Errorf("unexpected rows in %v: %q", name, rows)
+				}
+			}
+
+			if unmapped := options.FieldAudit.Report().Unmapped; !slices.Equal(unmapped, test.unmapped) {
+				t.Errorf("unmapped %q, expected %q", unmapped, test.unmapped)
+			}
+		})
+	}
+}
+
+func TestMappingCastStats(t *testing.T) {
+	spec := `{"entity": "things", "id": "id", "tables": [
+		{"name": "things", "columns": [
+			{"name": "id", "path": "id", "type": "TEXT"},
+			{"name": "year", "path": "year", "type": "INTEGER"},
+			{"name": "year_text", "path": "year", "type": "TEXT"},
+			{"name": "parent", "path": "parent", "fallback": ["parent.id"], "type": "TEXT"}
+		]},
+		{"name": "things_years", "from": "dates", "columns": [
+			{"name": "year", "path": "$.year", "type": "INTEGER"}
+		]}
+	]}`
+	records := []string{
+		`{"id": "T1", "year": 2020, "dates": {}, "parent": {"id": "P1"}}`,
+		`{"id": "T2", "year": "2021", "parent": "P2"}`,
+		`{"id": "T3", "year": null, "dates": {}}`,
+	}
+
+	options := NewOptions()
+	options.CastStats = NewCastStats("things", false)
+	if err := convertMapping(mustParseMapping(t, spec), jsonLines(t, records...), NewMemorySink(), 0, options); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		column   string
+		path     string
+		expected string
+		// Counts as ok/absent/null/wrong type
+		counts string
+	}{
+		{column: "id", path: "id", expected: "string", counts: "3/0/0/0"},
+		{column: "things.id", path: "id", expected: "string", counts: "3/0/0/0"},
+		{column: "things.year", path: "year", expected: "number", counts: "1/0/1/1"},
+		{column: "things.year_text", path: "year", expected: "string", counts: "1/0/1/1"},
+		// The object T1 has at parent is not counted as a wrong type, the fallback supplied its value,
+		// while T3 has neither and counts as absent for the last path tried
+		{column: "things.parent", path: "parent", expected: "string", counts: "1/0/0/0"},
+		{column: "things.parent", path: "parent", expected: "object", counts: "1/1/0/0"},
+		{column: "things.parent", path: "parent.id", expected: "string", counts: "1/0/0/0"},
+		{column: "things_years", path: "dates", expected: "object", counts: "2/1/0/0"},
+		{column: "things_years.year", path: "year", expected: "number", counts: "1/0/1/0"},
+	}
+
+	counts := options.CastStats.Counts()
+	if len(counts) != len(tests) {
+		t.Errorf("%v counts, expected %v", len(counts), len(tests))
+	}
+	for _, test := range tests {
+		t.Run(test.column+" "+test.expected, func(t *testing.T) {
+			i := slices.IndexFunc(counts, func(c CastCounts) bool {
+				return c.ColumnName() == test.column && c.Path == test.path && c.Expected == test.expected
+			})
+			if i < 0 {
+				t.Fatalf("no counts")
+			}
+			c := counts[i]
+			if got := fmt.Sprintf("%v/%v/%v/%v", c.Ok, c.Absent, c.Null, c.WrongTypeTotal()); got != test.counts {
+				t.Errorf("counts %v, expected %v", got, test.counts)
+			}
+		})
+	}
+}
+
+func TestMappingExtend(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		err    string
+		tables []string
+	}{
+		{
+			name:   "new table",
+			spec:   `{"entity": "works", "tables": [{"name": "works_extra", "columns": [{"name": "work_id", "path": "id", "type": "TEXT"}]}]}`,
+			tables: []string{"works_extra"},
+		},
+		{
+			name:   "more rows for a built-in table",
+			spec:   `{"entity": "works", "tables": [{"name": "works_referenced_works", "explode": "cited", "no_import": true, "columns": [{"name": "work_id", "path": "$.id", "type": "TEXT"}, {"name": "referenced_work_id", "path": "@", "type": "TEXT"}]}]}`,
+			tables: []string{"works_referenced_works"},
+		},
+		{
+			name: "built-in table with other columns",
+			spec: `{"entity": "works", "tables": [{"name": "works_referenced_works", "columns": [{"name": "work_id", "path": "id", "type": "TEXT"}]}]}`,
+			err:  "mapping works has table works_referenced_works twice with different columns",
+		},
+	}
+
+	builtin := mustLoadBuiltinMapping("works")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extended, err := builtin.extend(mustParseMapping(t, test.spec))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("error %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(extended.Tables) != len(builtin.Tables)+len(test.tables) {
+				t.Errorf("%v tables, expected %v", len(extended.Tables), len(builtin.Tables)+len(test.tables))
+			}
+			for i, name := range test.tables {
+				if table := extended.Tables[len(builtin.Tables)+i]; table.Name != name || table.builtin {
+					t.Errorf("table %v (built-in %v), expected %v added by the extension", table.Name, table.builtin, name)
+				}
+			}
+			if len(builtin.Tables) != len(mustLoadBuiltinMapping("works").Tables) {
+				t.Errorf("the built-in mapping was changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package converters
+
+import (
+	"slices"
+	"sync"
+)
+
+type MemoryTable struct {
+	Entity string
+	Schema TableSchema
+	Rows   []Row
+}
+
+// Keeps all rows in memory, with the chunks of a table appended to each other.
+// Meant for tests and for embedding the converters where the output is small
+type MemorySink struct {
+	mu sync.Mutex
+	// By entity type and table, all chunks at index 0
+	tables      map[tableChunkKey]*MemoryTable
+	deadLetters []DeadLetter
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{tables: map[tableChunkKey]*MemoryTable{}}
+}
+
+type memoryRowWriter struct {
+	sink  *MemorySink
+	table *MemoryTable
+}
+
+func (s *MemorySink) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tableChunkKey{entity: entity, table: table.Name}
+	memoryTable := s.tables[key]
+	if memoryTable == nil {
+		memoryTable = &MemoryTable{Entity: entity, Schema: table}
+		s.tables[key] = memoryTable
+	}
+	return &memoryRowWriter{sink: s, table: memoryTable}, nil
+}
+
+func (w *memoryRowWriter) WriteRow(row Row) error {
+	w.sink.mu.Lock()
+	w.table.Rows = append(w.table.Rows, slices.Clone(row))
+	w.sink.mu.Unlock()
+	return nil
+}
+
+func (w *memoryRowWriter) Close() error {
+	return nil
+}
+
+// Returns a table of an entity type by name, or nil if it was never opened
+func (s *MemorySink) Table(entity string, name string) *MemoryTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tables[tableChunkKey{entity: entity, table: name}]
+}
+
+type memoryDeadLetterWriter struct {
+	sink *MemorySink
+}
+
+func (s *MemorySink) OpenDeadLetters(entity string, chunk int) (DeadLetterWriter, error) {
+	return memoryDeadLetterWriter{s}, nil
+}
+
+func (w memoryDeadLetterWriter) WriteDeadLetter(letter DeadLetter) error {
+	w.sink.mu.Lock()
+	w.sink.deadLetters = append(w.sink.deadLetters, letter)
+	w.sink.mu.Unlock()
+	return nil
+}
+
+func (w memoryDeadLetterWriter) Close() error {
+	return nil
+}
+
+func (s *MemorySink) DeadLetters() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.deadLetters)
+}
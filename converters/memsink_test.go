@@ -0,0 +1,32 @@
+package converters
+
+import "testing"
+
+func TestMemorySinkKeepsEntityTypesApart(t *testing.T) {
+	sink := NewMemorySink()
+	schema := TableSchema{Name: "things_ids", Columns: []Column{{Name: "id", Type: "TEXT"}}}
+	for _, entity := range []string{"things", "other_things"} {
+		for chunk := range 2 {
+			writer, err := sink.OpenTable(entity, schema, chunk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.WriteRow(Row{entity}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, entity := range []string{"things", "other_things"} {
+		table := sink.Table(entity, "things_ids")
+		if table == nil {
+			t.Fatalf("%v: no table", entity)
+		}
+		if table.Entity != entity || len(table.Rows) != 2 || table.Rows[0][0] != entity || table.Rows[1][0] != entity {
+			t.Errorf("%v: %v rows of %v %v, expected both chunks of its own", entity, len(table.Rows), table.Entity, table.Rows)
+		}
+	}
+	if sink.Table("missing", "things_ids") != nil {
+		t.Errorf("table of an entity type that never opened it")
+	}
+}
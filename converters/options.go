@@ -0,0 +1,63 @@
+package converters
+
+import "iter"
+
+// Settings of a conversion, shared by all of its chunks. They fully apply to entity types
+// converted by a mapping, Go converters registered with RegisterEntityType only get the
+// records that match the filters, without field audit, cast statistics or legacy columns
+type Options struct {
+	// Records of an entity type are only converted if they match all of its filters
+	Filters map[string][]*Filter
+	// Also fill the delimited topics.keywords and raw topics.siblings columns,
+	// superseded by topics_keywords and topics_siblings
+	TopicsLegacyColumns bool
+	// Records the JSON paths seen and read, if set
+	FieldAudit *FieldAudit
+	// Counts fields that were absent, null or of an unexpected type, if set
+	CastStats *CastStats
+}
+
+// Options used by Convert, without filters or statistics
+func NewOptions() *Options {
+	return &Options{Filters: map[string][]*Filter{}, TopicsLegacyColumns: true}
+}
+
+// Only converts the records of entity that match filter, in addition to any filters added before
+func (o *Options) AddFilter(entity string, filter *Filter) {
+	if o.Filters == nil {
+		o.Filters = map[string][]*Filter{}
+	}
+	o.Filters[entity] = append(o.Filters[entity], filter)
+}
+
+func (o *Options) matchesFilters(entity string, record map[string]any) bool {
+	for _, filter := range o.Filters[entity] {
+		if !filter.Match(record) {
+			return false
+		}
+	}
+	return true
+}
+
+// Leaves out the records of entity that don't match its filters, for Go converters.
+// Lines that failed to decode are passed on for the converter to report
+func (o *Options) filterLines(entity string, lines iter.Seq2[JsonLine, error]) iter.Seq2[JsonLine, error] {
+	if o == nil || len(o.Filters[entity]) == 0 {
+		return lines
+	}
+	return func(yield func(JsonLine, error) bool) {
+		for line, err := range lines {
+			if err == nil && !o.matchesFilters(entity, line.Data) {
+				continue
+			}
+			if !yield(line, err) {
+				return
+			}
+		}
+	}
+}
+
+// Whether the JSON paths of the values read have to be tracked
+func (o *Options) tracksPaths() bool {
+	return o.FieldAudit != nil || o.CastStats != nil
+}
@@ -0,0 +1,136 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// Parquet column for a DuckDB type. Dates and timestamps are kept as the strings OpenAlex has,
+// DuckDB casts them when inserting into the typed tables
+func parquetNode(sqlType string) parquet.Node {
+	switch sqlType {
+	case "INTEGER", "BIGINT", "SMALLINT":
+		return parquet.Optional(parquet.Int(64))
+	case "REAL", "DOUBLE", "FLOAT", "DECIMAL":
+		return parquet.Optional(parquet.Leaf(parquet.DoubleType))
+	case "BOOLEAN":
+		return parquet.Optional(parquet.Leaf(parquet.BooleanType))
+	case "JSON":
+		return parquet.Optional(parquet.JSON())
+	default:
+		return parquet.Optional(parquet.String())
+	}
+}
+
+// Converts a Row value into a parquet value of the column's physical type
+func parquetValue(value any, column Column, kind parquet.Kind) (parquet.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return parquet.NullValue(), nil
+	case json.Number:
+		switch kind {
+		case parquet.Int64:
+			if i, err := v.Int64(); err == nil {
+				return parquet.Int64Value(i), nil
+			}
+			// Integers written as 1.0 or 1e3
+			f, err := v.Float64()
+			if err != nil || f != math.Trunc(f) {
+				return parquet.Value{}, fmt.Errorf("%v: %v is not an integer", column.Name, v)
+			}
+			return parquet.Int64Value(int64(f)), nil
+		case parquet.Double:
+			f, err := v.Float64()
+			if err != nil {
+				return parquet.Value{}, fmt.Errorf("%v: %w", column.Name, err)
+			}
+			return parquet.DoubleValue(f), nil
+		default:
+			return parquet.ByteArrayValue([]byte(v)), nil
+		}
+	case int:
+		return parquet.Int64Value(int64(v)), nil
+	case bool:
+		return parquet.BooleanValue(v), nil
+	case string:
+		return parquet.ByteArrayValue([]byte(v)), nil
+	case json.RawMessage:
+		return parquet.ByteArrayValue(v), nil
+	default:
+		return parquet.Value{}, fmt.Errorf("%v: unsupported value %T", column.Name, value)
+	}
+}
+
+// Rows are built value by value, as writing Go maps or structs would turn
+// zero values of optional columns (0, "", false) into nulls
+type parquetRowEncoder struct {
+	table  TableSchema
+	writer *parquet.Writer
+	// Leaf column index and physical type of every table column
+	columnIndexes []int
+	kinds         []parquet.Kind
+	row           []parquet.Row
+}
+
+func (p *parquetRowEncoder) encode(row Row) error {
+	values := make(parquet.Row, len(row))
+	for i, value := range row {
+		converted, err := parquetValue(value, p.table.Columns[i], p.kinds[i])
+		if err != nil {
+			return err
+		}
+
+		definitionLevel := 1
+		if converted.IsNull() {
+			definitionLevel = 0
+		}
+		values[p.columnIndexes[i]] = converted.Level(0, definitionLevel, p.columnIndexes[i])
+	}
+
+	p.row[0] = values
+	_, err := p.writer.WriteRows(p.row)
+	return err
+}
+
+func (p *parquetRowEncoder) close() error {
+	return p.writer.Close()
+}
+
+var FormatParquet = &FileFormat{
+	Name:      "parquet",
+	Extension: ".parquet",
+	newEncoder: func(w io.Writer, table TableSchema) (rowEncoder, error) {
+		group := parquet.Group{}
+		for _, column := range table.Columns {
+			group[column.Name] = parquetNode(column.Type)
+		}
+
+		schema := parquet.NewSchema(table.Name, group)
+
+		encoder := &parquetRowEncoder{
+			table:         table,
+			writer:        parquet.NewWriter(w, schema, parquet.Compression(&zstd.Codec{})),
+			columnIndexes: make([]int, len(table.Columns)),
+			kinds:         make([]parquet.Kind, len(table.Columns)),
+			row:           make([]parquet.Row, 1),
+		}
+		for i, column := range table.Columns {
+			leaf, ok := schema.Lookup(column.Name)
+			if !ok {
+				return nil, fmt.Errorf("%v: no parquet column %v", table.Name, column.Name)
+			}
+			encoder.columnIndexes[i] = leaf.ColumnIndex
+			encoder.kinds[i] = leaf.Node.Type().Kind()
+		}
+		return encoder, nil
+	},
+	// parquet.Group orders columns by name, so they are always selected in table order
+	duckdbReader: func(path string, table TableSchema, hive bool) string {
+		return fmt.Sprintf("(SELECT %v FROM read_parquet('%v'%v))", duckdbColumnList(table), path, hiveOption(hive))
+	},
+}
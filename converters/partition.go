@@ -0,0 +1,158 @@
+package converters
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+// Optionally implemented by sinks that need to know which record the following rows belong to.
+// startRecord is called by the converters before converting each record of the entity types
+// routesRecords holds for
+type recordRouter interface {
+	routesRecords(entity string) bool
+	startRecord(entity string, chunk int, record map[string]any)
+}
+
+// The sink as a recordRouter if it routes the rows of entity by record, otherwise nil
+func recordRouterOf(sink Sink, entity string) recordRouter {
+	if router, ok := sink.(recordRouter); ok && router.routesRecords(entity) {
+		return router
+	}
+	return nil
+}
+
+// Writes every row of a record, in all tables of its entity type, to part hash(id) % parts
+// of the wrapped sink, whichever chunk converted it. Joining a table with its child tables
+// then only needs the parts with the same number.
+//
+// Parts are shared by all chunks and stay open until Close, which has to be called
+// once all chunks of an entity type are converted. Rows of records without an id go to part 0
+type HashPartitionSink struct {
+	sink  Sink
+	parts int
+
+	mu sync.Mutex
+	// Open parts by entity, table and part number
+	writers map[tableChunkKey]*sharedRowWriter
+	// Part of the record being converted, by entity and chunk
+	current map[tableChunkKey]*int
+}
+
+// Table (empty for state shared by a chunk's tables) and chunk or part number of an entity type
+type tableChunkKey struct {
+	entity string
+	table  string
+	index  int
+}
+
+func NewHashPartitionSink(sink Sink, parts int) *HashPartitionSink {
+	return &HashPartitionSink{
+		sink:    sink,
+		parts:   parts,
+		writers: map[tableChunkKey]*sharedRowWriter{},
+		current: map[tableChunkKey]*int{},
+	}
+}
+
+func hashPartition(record map[string]any, parts int) int {
+	id, ok := record["id"].(string)
+	if !ok {
+		return 0
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(parts))
+}
+
+// Part the current record of a chunk is written to, shared by all table writers of that chunk
+func (s *HashPartitionSink) currentPart(entity string, chunk int) *int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tableChunkKey{entity: entity, index: chunk}
+	part := s.current[key]
+	if part == nil {
+		part = new(int)
+		s.current[key] = part
+	}
+	return part
+}
+
+func (s *HashPartitionSink) routesRecords(entity string) bool {
+	return true
+}
+
+func (s *HashPartitionSink) startRecord(entity string, chunk int, record map[string]any) {
+	*s.currentPart(entity, chunk) = hashPartition(record, s.parts)
+}
+
+func (s *HashPartitionSink) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	s.mu.Lock()
+	parts := make([]*sharedRowWriter, s.parts)
+	for i := range parts {
+		key := tableChunkKey{entity: entity, table: table.Name, index: i}
+		if s.writers[key] == nil {
+			writer, err := s.sink.OpenTable(entity, table, i)
+			if err != nil {
+				s.mu.Unlock()
+				return nil, err
+			}
+			s.writers[key] = &sharedRowWriter{writer: writer}
+		}
+		parts[i] = s.writers[key]
+	}
+	s.mu.Unlock()
+
+	return &hashPartitionRowWriter{parts: parts, current: s.currentPart(entity, chunk)}, nil
+}
+
+func (s *HashPartitionSink) selectedColumns(entity string, table TableSchema) []bool {
+	return selectedColumns(s.sink, entity, table)
+}
+
+// Dead letters are kept per chunk, as they point back at the input anyway
+func (s *HashPartitionSink) OpenDeadLetters(entity string, chunk int) (DeadLetterWriter, error) {
+	if deadLetterSink, ok := s.sink.(DeadLetterSink); ok {
+		return deadLetterSink.OpenDeadLetters(entity, chunk)
+	}
+	return discardDeadLetterWriter{}, nil
+}
+
+// Closes every open part
+func (s *HashPartitionSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for key, writer := range s.writers {
+		errs = append(errs, writer.writer.Close())
+		delete(s.writers, key)
+	}
+	clear(s.current)
+	return errors.Join(errs...)
+}
+
+type sharedRowWriter struct {
+	mu     sync.Mutex
+	writer RowWriter
+}
+
+// Writes to the part of the chunk's current record. Closing it leaves the parts open for other chunks
+type hashPartitionRowWriter struct {
+	parts   []*sharedRowWriter
+	current *int
+}
+
+func (w *hashPartitionRowWriter) WriteRow(row Row) error {
+	part := w.parts[*w.current]
+	part.mu.Lock()
+	defer part.mu.Unlock()
+
+	return part.writer.WriteRow(row)
+}
+
+func (w *hashPartitionRowWriter) Close() error {
+	return nil
+}
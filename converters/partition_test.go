@@ -0,0 +1,146 @@
+package converters
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Keeps the rows written to every table and chunk, which are the parts behind a HashPartitionSink
+type chunkSink struct {
+	mu   sync.Mutex
+	rows map[tableChunkKey][]Row
+}
+
+type chunkRowWriter struct {
+	sink *chunkSink
+	key  tableChunkKey
+}
+
+func (s *chunkSink) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	return &chunkRowWriter{sink: s, key: tableChunkKey{entity: entity, table: table.Name, index: chunk}}, nil
+}
+
+func (w *chunkRowWriter) WriteRow(row Row) error {
+	w.sink.mu.Lock()
+	defer w.sink.mu.Unlock()
+
+	w.sink.rows[w.key] = append(w.sink.rows[w.key], slices.Clone(row))
+	return nil
+}
+
+func (w *chunkRowWriter) Close() error {
+	return nil
+}
+
+func writeGzipLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := gzip.NewWriter(file)
+	if _, err := archive.Write([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashPartitionSinkKeepsRecordsTogether(t *testing.T) {
+	tests := []struct {
+		name     string
+		parts    int
+		chunks   int
+		pipeline Pipeline
+	}{
+		{name: "one part", parts: 1, chunks: 2},
+		{name: "sequential", parts: 4, chunks: 1},
+		{name: "more parts than chunks", parts: 5, chunks: 3},
+		{name: "fewer parts than chunks", parts: 2, chunks: 4},
+		{name: "convert workers", parts: 4, chunks: 2, pipeline: Pipeline{ConvertWorkers: 4, Buffer: 8}},
+		{name: "decode workers and table encoders", parts: 3, chunks: 2, pipeline: Pipeline{DecodeWorkers: 3, TableEncoders: true}},
+	}
+
+	const numRecords = 60
+	var records []string
+	for i := range numRecords {
+		records = append(records, fmt.Sprintf(`{
+			"id": "https://openalex.org/W%[1]v",
+			"publication_year": %[2]v,
+			"ids": {"openalex": "https://openalex.org/W%[1]v", "doi": "https://doi.org/10.1/%[1]v"},
+			"authorships": [
+				{"author_position": "first", "author": {"id": "https://openalex.org/A%[1]v"}, "institutions": [{"id": "https://openalex.org/I%[1]v"}]},
+				{"author_position": "last", "author": {"id": "https://openalex.org/A%[3]v"}, "institutions": []}
+			],
+			"locations": [{"source": {"id": "https://openalex.org/S%[1]v"}, "is_oa": true}, {"is_oa": false}],
+			"primary_location": {"source": {"id": "https://openalex.org/S%[1]v"}, "is_oa": true},
+			"best_oa_location": {"source": {"id": "https://openalex.org/S%[1]v"}, "is_oa": true},
+			"mesh": [{"descriptor_ui": "D%[1]v", "is_major_topic": true}],
+			"biblio": {"volume": "%[1]v", "first_page": "1"},
+			"topics": [{"id": "https://openalex.org/T%[1]v", "score": 0.5}],
+			"concepts": [{"id": "https://openalex.org/C%[1]v", "score": 0.5}],
+			"open_access": {"is_oa": true, "oa_status": "gold"},
+			"apc_list": {"value": %[1]v, "currency": "USD"},
+			"apc_paid": {"value": %[1]v, "currency": "USD"},
+			"referenced_works": ["https://openalex.org/W%[3]v", "https://openalex.org/W%[4]v"],
+			"related_works": ["https://openalex.org/W%[4]v"]
+		}`, i, 2000+i%7, i+1000, i+2000))
+		records[i] = strings.Join(strings.Fields(records[i]), " ")
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			parts := &chunkSink{rows: map[tableChunkKey][]Row{}}
+			sink := NewHashPartitionSink(parts, test.parts)
+
+			// Records are dealt to the chunks round robin, so every part gets records from every chunk
+			for chunk := range test.chunks {
+				var lines []string
+				for i := chunk; i < numRecords; i += test.chunks {
+					lines = append(lines, records[i])
+				}
+				path := filepath.Join(dir, fmt.Sprint("part_", chunk, ".gz"))
+				writeGzipLines(t, path, lines)
+
+				if err := test.pipeline.Convert(TypeWorks, slices.Values([]string{path}), sink, chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			rows := map[string]int{}
+			for key, tableRows := range parts.rows {
+				if key.index < 0 || key.index >= test.parts {
+					t.Errorf("%v written to part %v of %v", key.table, key.index, test.parts)
+				}
+				for _, row := range tableRows {
+					id, _ := row[0].(string)
+					if want := hashPartition(map[string]any{"id": id}, test.parts); key.index != want {
+						t.Errorf("%v row of %v in part %v, expected %v", key.table, id, key.index, want)
+					}
+					rows[key.table]++
+				}
+			}
+
+			// Every table got rows, so every one of them was checked
+			for _, table := range TypeWorks.Tables {
+				if rows[table.Name] < numRecords {
+					t.Errorf("%v: %v rows, expected at least one per record", table.Name, rows[table.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package converters
+
+import (
+	"reflect"
+)
+
+func joinPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func mapKey(m map[string]any) uintptr {
+	return reflect.ValueOf(m).Pointer()
+}
+
+func sliceKey(arr []any) uintptr {
+	if len(arr) == 0 {
+		return 0
+	}
+	return reflect.ValueOf(arr).Pointer()
+}
@@ -0,0 +1,462 @@
+package converters
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"iter"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Splits the conversion of a chunk into stages running on their own goroutines:
+// reading and decompressing the input, decoding JSON, converting records and encoding each table.
+// Stages are connected by bounded channels, so a slow stage holds back the ones before it,
+// and records are written in input order, giving the same files as converting on a single goroutine.
+//
+// The zero value converts a chunk on the calling goroutine
+type Pipeline struct {
+	// Goroutines decoding JSON lines, read and decompressed by another one
+	DecodeWorkers int
+	// Goroutines running the entity type's converter, each on different records
+	ConvertWorkers int
+	// Write every table from its own goroutine. Ignored for sinks that route the entity type's rows
+	// by record, which need the rows of a record to be written before the next record starts:
+	// a HashPartitionSink, or a FileSink with hive partitions for the entity type,
+	// also when wrapped by a Projection
+	TableEncoders bool
+	// Records that may be decoded or converted but not yet written, 1024 if not set
+	Buffer int
+	// Settings of the conversion, NewOptions() if not set
+	Options *Options
+}
+
+// Whether the tables of entity are written from their own goroutines when converting to sink
+func (p Pipeline) tableEncoders(sink Sink, entity string) bool {
+	return p.TableEncoders && recordRouterOf(sink, entity) == nil
+}
+
+// Lines decoded in one go by a decode worker
+const pipelineBatchSize = 256
+
+func (p Pipeline) staged() bool {
+	return p.DecodeWorkers > 1 || p.ConvertWorkers > 1 || p.TableEncoders
+}
+
+func (p Pipeline) buffer() int {
+	if p.Buffer > 0 {
+		return p.Buffer
+	}
+	return 1024
+}
+
+// Converts the gzipped JSON lines files of a chunk with entityType, like
+// entityType.ConvertWith(ReadJsonLinesAll(gzipPaths), sink, chunk, p.Options)
+func (p Pipeline) Convert(entityType EntityType, gzipPaths iter.Seq[string], sink Sink, chunk int) error {
+	lines := ReadJsonLinesAll(gzipPaths)
+	if p.DecodeWorkers > 1 {
+		lines = p.decodeLines(gzipPaths)
+	}
+	if !p.staged() {
+		return entityType.ConvertWith(lines, sink, chunk, p.Options)
+	}
+	return p.convertLines(entityType, lines, sink, chunk)
+}
+
+type rawLine struct {
+	JsonLine
+	err error
+}
+
+type lineBatch struct {
+	seq   int
+	lines []rawLine
+}
+
+// Reads and decompresses the files on one goroutine, decoding batches of lines on DecodeWorkers others
+func (p Pipeline) decodeLines(gzipPaths iter.Seq[string]) iter.Seq2[JsonLine, error] {
+	return func(yield func(JsonLine, error) bool) {
+		done := make(chan struct{})
+		defer close(done)
+
+		raw := make(chan lineBatch, p.DecodeWorkers)
+		decoded := make(chan lineBatch, p.DecodeWorkers)
+
+		go func() {
+			defer close(raw)
+			readLineBatches(gzipPaths, func(batch lineBatch) bool {
+				select {
+				case raw <- batch:
+					return true
+				case <-done:
+					return false
+				}
+			})
+		}()
+
+		wg := new(sync.WaitGroup)
+		for range p.DecodeWorkers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for batch := range raw {
+					for i := range batch.lines {
+						line := &batch.lines[i]
+						if line.err == nil {
+							line.Data, line.err = DecodeJsonLine(line.Raw)
+						}
+					}
+
+					select {
+					case decoded <- batch:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(decoded)
+		}()
+
+		// Batches finish out of order, so they are held back until the ones before them arrive
+		pending := map[int]lineBatch{}
+		next := 0
+		for batch := range decoded {
+			pending[batch.seq] = batch
+			for {
+				batch, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				for _, line := range batch.lines {
+					if !yield(line.JsonLine, line.err) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Reads undecoded lines into batches, keeping a copy of every line
+func readLineBatches(gzipPaths iter.Seq[string], send func(batch lineBatch) bool) {
+	batch := lineBatch{}
+	add := func(line rawLine) bool {
+		batch.lines = append(batch.lines, line)
+		if len(batch.lines) < pipelineBatchSize {
+			return true
+		}
+
+		full := batch
+		batch = lineBatch{seq: batch.seq + 1}
+		return send(full)
+	}
+
+	for path := range gzipPaths {
+		if !readRawLines(path, add) {
+			return
+		}
+	}
+	if len(batch.lines) > 0 {
+		send(batch)
+	}
+}
+
+func readRawLines(path string, add func(line rawLine) bool) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return add(rawLine{JsonLine{Source: path}, err})
+	}
+	defer file.Close()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		return add(rawLine{JsonLine{Source: path}, err})
+	}
+	defer gzReader.Close()
+
+	scanner := bufio.NewScanner(gzReader)
+	scanner.Buffer(nil, maxLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if !add(rawLine{JsonLine{Source: path, Line: lineNumber, Raw: slices.Clone(scanner.Bytes())}, nil}) {
+			return false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return add(rawLine{JsonLine{Source: path, Line: lineNumber + 1}, err})
+	}
+	return true
+}
+
+type pipelineLine struct {
+	seq  int
+	line JsonLine
+	err  error
+}
+
+// Rows and dead letters written while converting one record
+type pipelineRecord struct {
+	seq         int
+	record      map[string]any
+	rows        []pipelineRow
+	deadLetters []DeadLetter
+}
+
+type pipelineRow struct {
+	table *pipelineTable
+	row   Row
+}
+
+// A table of the wrapped sink, opened once and shared by all convert workers
+type pipelineTable struct {
+	writer RowWriter
+	rows   chan Row
+	done   chan struct{}
+}
+
+// Runs ConvertWorkers instances of the converter on the lines, buffering the rows of each record
+// and writing them to sink in input order
+func (p Pipeline) convertLines(entityType EntityType, lines iter.Seq2[JsonLine, error], sink Sink, chunk int) error {
+	router := recordRouterOf(sink, entityType.Name)
+	tableEncoders := p.tableEncoders(sink, entityType.Name)
+
+	// Taken for every record handed to a convert worker and given back once it is written,
+	// bounding how far the workers can get ahead of the slowest record
+	inFlight := make(chan struct{}, p.buffer())
+	work := make(chan pipelineLine, p.buffer())
+	converted := make(chan *pipelineRecord, p.buffer())
+
+	var tablesMu sync.Mutex
+	tables := map[string]*pipelineTable{}
+	openTable := func(entity string, table TableSchema) (*pipelineTable, error) {
+		tablesMu.Lock()
+		defer tablesMu.Unlock()
+
+		if t, exists := tables[table.Name]; exists {
+			return t, nil
+		}
+
+		writer, err := sink.OpenTable(entity, table, chunk)
+		if err != nil {
+			return nil, err
+		}
+		t := &pipelineTable{writer: writer}
+		if tableEncoders {
+			t.rows = make(chan Row, p.buffer())
+			t.done = make(chan struct{})
+			go func() {
+				defer close(t.done)
+				for row := range t.rows {
+					if err := writer.WriteRow(row); err != nil {
+						log.Println(err)
+					}
+				}
+			}()
+		}
+		tables[table.Name] = t
+		return t, nil
+	}
+
+	var deadLetters DeadLetterWriter
+	if deadLetterSink, ok := sink.(DeadLetterSink); ok {
+		writer, err := deadLetterSink.OpenDeadLetters(entityType.Name, chunk)
+		if err != nil {
+			return err
+		}
+		deadLetters = writer
+	}
+
+	go func() {
+		defer close(work)
+		seq := 0
+		for line, err := range lines {
+			// Lines decoded by decodeLines already have their own copy
+			if p.DecodeWorkers <= 1 {
+				line.Raw = slices.Clone(line.Raw)
+			}
+			inFlight <- struct{}{}
+			work <- pipelineLine{seq: seq, line: line, err: err}
+			seq++
+		}
+	}()
+
+	workers := max(p.ConvertWorkers, 1)
+	wg := new(sync.WaitGroup)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			worker := &pipelineWorker{sink: sink, openTable: openTable, converted: converted}
+			if err := entityType.ConvertWith(worker.lines(work), worker, chunk, p.Options); err != nil {
+				log.Println(err)
+			}
+			worker.flush()
+
+			// Whatever the converter didn't read still has to be accounted for
+			for line := range work {
+				converted <- &pipelineRecord{seq: line.seq}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(converted)
+	}()
+
+	// Records finish out of order, so they are held back until the ones before them are written
+	pending := map[int]*pipelineRecord{}
+	next := 0
+	for record := range converted {
+		pending[record.seq] = record
+		for {
+			record, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if router != nil && record.record != nil {
+				router.startRecord(entityType.Name, chunk, record.record)
+			}
+			for _, row := range record.rows {
+				if tableEncoders {
+					row.table.rows <- row.row
+				} else if err := row.table.writer.WriteRow(row.row); err != nil {
+					log.Println(err)
+				}
+			}
+			if deadLetters != nil {
+				for _, letter := range record.deadLetters {
+					if err := deadLetters.WriteDeadLetter(letter); err != nil {
+						log.Println(err)
+					}
+				}
+			}
+			<-inFlight
+		}
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(tables)) {
+		t := tables[name]
+		if tableEncoders {
+			close(t.rows)
+			<-t.done
+		}
+		errs = append(errs, t.writer.Close())
+	}
+	if deadLetters != nil {
+		errs = append(errs, deadLetters.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// The sink and input lines seen by one instance of a converter.
+// A converter handles its records one at a time, so everything it writes
+// between reading two lines belongs to the first of them
+type pipelineWorker struct {
+	// Wrapped sink, only asked which tables and columns it writes
+	sink      Sink
+	openTable func(entity string, table TableSchema) (*pipelineTable, error)
+	converted chan<- *pipelineRecord
+	current   *pipelineRecord
+}
+
+func (w *pipelineWorker) lines(work <-chan pipelineLine) iter.Seq2[JsonLine, error] {
+	return func(yield func(JsonLine, error) bool) {
+		for {
+			// The previous record is done once the next line is asked for,
+			// and it may be the one the others are waiting on before any more lines are read
+			w.flush()
+			line, ok := <-work
+			if !ok {
+				return
+			}
+
+			w.current = &pipelineRecord{seq: line.seq}
+			if !yield(line.line, line.err) {
+				return
+			}
+		}
+	}
+}
+
+// Hands the current record on to be written
+func (w *pipelineWorker) flush() {
+	if w.current != nil {
+		w.converted <- w.current
+		w.current = nil
+	}
+}
+
+func (w *pipelineWorker) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	t, err := w.openTable(entity, table)
+	if err != nil {
+		return nil, err
+	}
+	return &pipelineRowWriter{worker: w, table: t}, nil
+}
+
+func (w *pipelineWorker) OpenDeadLetters(entity string, chunk int) (DeadLetterWriter, error) {
+	return pipelineDeadLetterWriter{w}, nil
+}
+
+func (w *pipelineWorker) selectedColumns(entity string, table TableSchema) []bool {
+	return selectedColumns(w.sink, entity, table)
+}
+
+func (w *pipelineWorker) routesRecords(entity string) bool {
+	return recordRouterOf(w.sink, entity) != nil
+}
+
+func (w *pipelineWorker) startRecord(entity string, chunk int, record map[string]any) {
+	if w.current != nil {
+		w.current.record = record
+	}
+}
+
+type pipelineRowWriter struct {
+	worker *pipelineWorker
+	table  *pipelineTable
+}
+
+func (r *pipelineRowWriter) WriteRow(row Row) error {
+	if r.worker.current == nil {
+		return errors.New("row written outside of a record")
+	}
+	r.worker.current.rows = append(r.worker.current.rows, pipelineRow{table: r.table, row: slices.Clone(row)})
+	return nil
+}
+
+// Tables are closed once all workers are done
+func (r *pipelineRowWriter) Close() error {
+	return nil
+}
+
+type pipelineDeadLetterWriter struct {
+	worker *pipelineWorker
+}
+
+func (d pipelineDeadLetterWriter) WriteDeadLetter(letter DeadLetter) error {
+	if d.worker.current == nil {
+		return errors.New("dead letter written outside of a record")
+	}
+	d.worker.current.deadLetters = append(d.worker.current.deadLetters, letter)
+	return nil
+}
+
+func (d pipelineDeadLetterWriter) Close() error {
+	return nil
+}
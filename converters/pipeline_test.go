@@ -0,0 +1,201 @@
+package converters
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipelineTableEncoders(t *testing.T) {
+	hiveSink := func() Sink {
+		sink := NewCsvSink(t.TempDir())
+		sink.HivePartitions["works"] = "publication_year"
+		return sink
+	}
+
+	tests := []struct {
+		name   string
+		sink   Sink
+		entity string
+		// Whether the tables are written from their own goroutines
+		tableEncoders bool
+	}{
+		{name: "file sink", sink: NewCsvSink(t.TempDir()), entity: "works", tableEncoders: true},
+		{name: "projected file sink", sink: NewProjection().Sink(NewCsvSink(t.TempDir())), entity: "works", tableEncoders: true},
+		{name: "memory sink", sink: NewMemorySink(), entity: "works", tableEncoders: true},
+		{name: "hive partitions", sink: hiveSink(), entity: "works"},
+		{name: "projected hive partitions", sink: NewProjection().Sink(hiveSink()), entity: "works"},
+		{name: "entity type without hive partitions", sink: NewProjection().Sink(hiveSink()), entity: "authors", tableEncoders: true},
+		{name: "hash partitions", sink: NewHashPartitionSink(NewMemorySink(), 2), entity: "works"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pipeline := Pipeline{TableEncoders: true}
+			if got := pipeline.tableEncoders(test.sink, test.entity); got != test.tableEncoders {
+				t.Errorf("table encoders %v, expected %v", got, test.tableEncoders)
+			}
+			if (Pipeline{}).tableEncoders(test.sink, test.entity) {
+				t.Errorf("table encoders without TableEncoders")
+			}
+		})
+	}
+}
+
+// Holds back the rows of the works table until works_ids got one, which only
+// happens if the tables are written from different goroutines
+type rendezvousSink struct {
+	*MemorySink
+	once    sync.Once
+	idsRow  chan struct{}
+	timeout time.Duration
+}
+
+type rendezvousRowWriter struct {
+	RowWriter
+	sink  *rendezvousSink
+	table string
+}
+
+func (s *rendezvousSink) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	writer, err := s.MemorySink.OpenTable(entity, table, chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &rendezvousRowWriter{RowWriter: writer, sink: s, table: table.Name}, nil
+}
+
+func (w *rendezvousRowWriter) WriteRow(row Row) error {
+	switch w.table {
+	case "works_ids":
+		w.sink.once.Do(func() { close(w.sink.idsRow) })
+	case "works":
+		select {
+		case <-w.sink.idsRow:
+		case <-time.After(w.sink.timeout):
+			return errors.New("works written before works_ids got a row")
+		}
+	}
+	return w.RowWriter.WriteRow(row)
+}
+
+func TestPipelineTableEncodersRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "part_000.gz")
+	writeGzipLines(t, path, []string{`{"id": "W1", "ids": {"openalex": "W1"}}`})
+
+	sink := &rendezvousSink{MemorySink: NewMemorySink(), idsRow: make(chan struct{}), timeout: 5 * time.Second}
+	if err := (Pipeline{TableEncoders: true}).Convert(TypeWorks, slices.Values([]string{path}), sink, 0); err != nil {
+		t.Fatal(err)
+	}
+	if rows := sink.Table("works", "works").Rows; len(rows) != 1 {
+		t.Errorf("works: %v rows, expected 1", len(rows))
+	}
+
+	// Written on the converting goroutine, the works row times out
+	sink = &rendezvousSink{MemorySink: NewMemorySink(), idsRow: make(chan struct{}), timeout: 50 * time.Millisecond}
+	if err := (Pipeline{}).Convert(TypeWorks, slices.Values([]string{path}), sink, 0); err != nil {
+		t.Fatal(err)
+	}
+	if rows := sink.Table("works", "works").Rows; len(rows) != 0 {
+		t.Errorf("works: %v rows, expected the sequential conversion to time out", len(rows))
+	}
+}
+
+func TestPipelineTableEncodersFileSink(t *testing.T) {
+	var records []string
+	for i := range 50 {
+		records = append(records, fmt.Sprintf(`{"id": "W%[1]v", "ids": {"openalex": "W%[1]v"}, "referenced_works": ["W%[2]v", "W%[3]v"]}`, i, i+1, i+2))
+	}
+	path := filepath.Join(t.TempDir(), "part_000.gz")
+	writeGzipLines(t, path, records)
+
+	convert := func(pipeline Pipeline) map[string][][]string {
+		sink := NewCsvSink(t.TempDir())
+		if err := pipeline.Convert(TypeWorks, slices.Values([]string{path}), NewProjection().Sink(sink), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		tables := map[string][][]string{}
+		for _, part := range sink.TakeParts() {
+			tables[part.Table] = readCsvPart(t, part.Path)
+		}
+		return tables
+	}
+
+	sequential := convert(Pipeline{})
+	encoded := convert(Pipeline{TableEncoders: true})
+	if len(sequential["works_referenced_works"]) != 100 {
+		t.Fatalf("works_referenced_works: %v rows, expected 100", len(sequential["works_referenced_works"]))
+	}
+	for table, rows := range sequential {
+		if !slices.EqualFunc(rows, encoded[table], slices.Equal) {
+			t.Errorf("%v differs with table encoders", table)
+		}
+	}
+}
+
+func TestPipelineKeepsInputOrder(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for file := range 3 {
+		var records []string
+		for i := range 40 {
+			id := file*100 + i
+			records = append(records, fmt.Sprintf(`{"id": "W%[1]v", "ids": {"openalex": "W%[1]v"}, "publication_year": %[2]v, "referenced_works": ["W%[3]v", "W%[4]v"], "concepts": [{"id": "C%[1]v", "score": 0.5}]}`, id, 2000+id%5, id+1, id+2))
+		}
+		// Rejected lines keep their place among the dead letters
+		records[17] = `{"id": "W` + fmt.Sprint(file*100+17) + `", "ids": nul}`
+		path := filepath.Join(dir, fmt.Sprint("part_", file, ".gz"))
+		writeGzipLines(t, path, records)
+		paths = append(paths, path)
+	}
+
+	convert := func(pipeline Pipeline) *MemorySink {
+		sink := NewMemorySink()
+		if err := pipeline.Convert(TypeWorks, slices.Values(paths), sink, 0); err != nil {
+			t.Fatal(err)
+		}
+		return sink
+	}
+
+	sequential := convert(Pipeline{})
+	if rows := len(sequential.Table("works", "works").Rows); rows != 3*39 {
+		t.Fatalf("works: %v rows, expected %v", rows, 3*39)
+	}
+	if letters := len(sequential.DeadLetters()); letters != 3 {
+		t.Fatalf("%v dead letters, expected 3", letters)
+	}
+
+	tests := []struct {
+		name     string
+		pipeline Pipeline
+	}{
+		{name: "convert workers", pipeline: Pipeline{ConvertWorkers: 4, Buffer: 8}},
+		{name: "convert workers without buffer", pipeline: Pipeline{ConvertWorkers: 3, Buffer: 1}},
+		{name: "decode workers", pipeline: Pipeline{DecodeWorkers: 3}},
+		{name: "decode and convert workers", pipeline: Pipeline{DecodeWorkers: 2, ConvertWorkers: 4}},
+		{name: "table encoders", pipeline: Pipeline{DecodeWorkers: 2, ConvertWorkers: 3, TableEncoders: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sink := convert(test.pipeline)
+			expected, got := formatTables(sequential), formatTables(sink)
+			for table, rows := range expected {
+				if !slices.Equal(got[table], rows) {
+					t.Errorf("%v: rows differ from the sequential conversion", table)
+				}
+			}
+			if len(got) != len(expected) {
+				t.Errorf("%v tables, expected %v", len(got), len(expected))
+			}
+			if !slices.Equal(sink.DeadLetters(), sequential.DeadLetters()) {
+				t.Errorf("dead letters %v, expected %v", sink.DeadLetters(), sequential.DeadLetters())
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package converters
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Which tables and columns of each entity type are written.
+// Mappings skip the tables and columns that aren't selected without reading them,
+// rows of other converters are dropped or trimmed before they reach the sink
+type Projection struct {
+	// Selected tables by entity type, entity types without any are written in full
+	tables map[string]map[string]struct{}
+	// Column selections by entity.table
+	columns map[string]columnSelection
+}
+
+// Either the only columns written or, with exclude, the ones skipped
+type columnSelection struct {
+	exclude bool
+	names   []string
+}
+
+func NewProjection() *Projection {
+	return &Projection{
+		tables:  map[string]map[string]struct{}{},
+		columns: map[string]columnSelection{},
+	}
+}
+
+func splitTableName(s string) (string, string, error) {
+	entity, table, ok := strings.Cut(s, ".")
+	if !ok || entity == "" || table == "" {
+		return "", "", fmt.Errorf("expected ENTITY.TABLE, got %q", s)
+	}
+	return entity, table, nil
+}
+
+// Parses a comma-separated list of ENTITY.TABLE.
+// Only the listed tables of an entity type are written, entity types not listed keep all of theirs
+func (p *Projection) SelectTables(s string) error {
+	for name := range strings.SplitSeq(s, ",") {
+		entity, table, err := splitTableName(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+
+		if p.tables[entity] == nil {
+			p.tables[entity] = map[string]struct{}{}
+		}
+		p.tables[entity][table] = struct{}{}
+	}
+	return nil
+}
+
+// Parses ENTITY.TABLE:COLUMN,COLUMN to only write the listed columns of a table,
+// or ENTITY.TABLE:-COLUMN,-COLUMN to write all but the listed ones
+func (p *Projection) SelectColumns(s string) error {
+	tableName, columnList, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("expected ENTITY.TABLE:COLUMNS, got %q", s)
+	}
+	if _, _, err := splitTableName(tableName); err != nil {
+		return err
+	}
+	if _, exists := p.columns[tableName]; exists {
+		return fmt.Errorf("columns of %v selected twice", tableName)
+	}
+
+	var selection columnSelection
+	for i, name := range strings.Split(columnList, ",") {
+		name = strings.TrimSpace(name)
+		excluded := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		if name == "" {
+			return fmt.Errorf("%v: empty column name", tableName)
+		}
+		if i == 0 {
+			selection.exclude = excluded
+		} else if excluded != selection.exclude {
+			return fmt.Errorf("%v: columns must either all be selected or all be skipped with -", tableName)
+		}
+		selection.names = append(selection.names, name)
+	}
+
+	p.columns[tableName] = selection
+	return nil
+}
+
+func (p *Projection) tableSelected(entity string, table string) bool {
+	tables, exists := p.tables[entity]
+	if !exists {
+		return true
+	}
+	_, selected := tables[table]
+	return selected
+}
+
+// Indexes of the columns of table that are written, or nil for all of them
+func (p *Projection) columnIndexes(entity string, table TableSchema) []int {
+	selection, exists := p.columns[entity+"."+table.Name]
+	if !exists {
+		return nil
+	}
+
+	var indexes []int
+	for i, column := range table.Columns {
+		if slices.Contains(selection.names, column.Name) != selection.exclude {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func projectTable(table TableSchema, indexes []int) TableSchema {
+	if indexes == nil {
+		return table
+	}
+
+	columns := make([]Column, len(indexes))
+	for i, index := range indexes {
+		columns[i] = table.Columns[index]
+	}
+	table.Columns = columns
+	return table
+}
+
+// Reports tables and columns that don't exist in entityTypes,
+// which is only known once every entity type and mapping is registered
+func (p *Projection) Check(entityTypes []EntityType) error {
+	findEntityType := func(name string) (EntityType, error) {
+		i := slices.IndexFunc(entityTypes, func(entityType EntityType) bool {
+			return entityType.Name == name
+		})
+		if i < 0 {
+			return EntityType{}, fmt.Errorf("unknown entity type %v", name)
+		}
+		return entityTypes[i], nil
+	}
+	findTable := func(entityType EntityType, name string) (TableSchema, error) {
+		i := slices.IndexFunc(entityType.Tables, func(table TableSchema) bool {
+			return table.Name == name
+		})
+		if i < 0 {
+			return TableSchema{}, fmt.Errorf("%v has no table %v", entityType.Name, name)
+		}
+		return entityType.Tables[i], nil
+	}
+
+	for entity, tables := range p.tables {
+		entityType, err := findEntityType(entity)
+		if err != nil {
+			return err
+		}
+		for table := range tables {
+			if _, err := findTable(entityType, table); err != nil {
+				return err
+			}
+		}
+	}
+
+	for tableName, selection := range p.columns {
+		entity, tableName, _ := splitTableName(tableName)
+		entityType, err := findEntityType(entity)
+		if err != nil {
+			return err
+		}
+		table, err := findTable(entityType, tableName)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range selection.names {
+			if !slices.ContainsFunc(table.Columns, func(column Column) bool { return column.Name == name }) {
+				return fmt.Errorf("%v has no column %v", table.Name, name)
+			}
+		}
+		if len(p.columnIndexes(entity, table)) == 0 {
+			return fmt.Errorf("no columns of %v left", table.Name)
+		}
+	}
+	return nil
+}
+
+// Entity types with only the selected tables and columns, for writing the import script.
+// Import scripts written by WriteSqlImport are kept as they are
+func (p *Projection) EntityTypes(entityTypes []EntityType) []EntityType {
+	projected := make([]EntityType, len(entityTypes))
+	for i, entityType := range entityTypes {
+		var tables []TableSchema
+		for _, table := range entityType.Tables {
+			if p.tableSelected(entityType.Name, table.Name) {
+				tables = append(tables, projectTable(table, p.columnIndexes(entityType.Name, table)))
+			}
+		}
+
+		entityType.Tables = tables
+		projected[i] = entityType
+	}
+	return projected
+}
+
+// Wraps sink so that only the selected tables and columns are written to it
+func (p *Projection) Sink(sink Sink) Sink {
+	return &projectionSink{projection: p, sink: sink}
+}
+
+type projectionSink struct {
+	projection *Projection
+	sink       Sink
+}
+
+func (s *projectionSink) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	if !s.projection.tableSelected(entity, table.Name) {
+		return discardRowWriter{}, nil
+	}
+
+	indexes := s.projection.columnIndexes(entity, table)
+	writer, err := s.sink.OpenTable(entity, projectTable(table, indexes), chunk)
+	if err != nil || indexes == nil {
+		return writer, err
+	}
+	return &projectedRowWriter{RowWriter: writer, indexes: indexes, row: make(Row, len(indexes))}, nil
+}
+
+func (s *projectionSink) OpenDeadLetters(entity string, chunk int) (DeadLetterWriter, error) {
+	if deadLetterSink, ok := s.sink.(DeadLetterSink); ok {
+		return deadLetterSink.OpenDeadLetters(entity, chunk)
+	}
+	return discardDeadLetterWriter{}, nil
+}
+
+func (s *projectionSink) selectedColumns(entity string, table TableSchema) []bool {
+	if !s.projection.tableSelected(entity, table.Name) {
+		return nil
+	}
+
+	indexes := s.projection.columnIndexes(entity, table)
+	written := selectedColumns(s.sink, entity, projectTable(table, indexes))
+	if indexes == nil || written == nil {
+		return written
+	}
+
+	selected := make([]bool, len(table.Columns))
+	for i, index := range indexes {
+		selected[index] = written[i]
+	}
+	return selected
+}
+
+func (s *projectionSink) routesRecords(entity string) bool {
+	return recordRouterOf(s.sink, entity) != nil
+}
+
+func (s *projectionSink) startRecord(entity string, chunk int, record map[string]any) {
+	if router := recordRouterOf(s.sink, entity); router != nil {
+		router.startRecord(entity, chunk, record)
+	}
+}
+
+type projectedRowWriter struct {
+	RowWriter
+	indexes []int
+	row     Row
+}
+
+func (w *projectedRowWriter) WriteRow(row Row) error {
+	for i, index := range w.indexes {
+		w.row[i] = row[index]
+	}
+	return w.RowWriter.WriteRow(w.row)
+}
+
+type discardRowWriter struct{}
+
+func (discardRowWriter) WriteRow(row Row) error { return nil }
+func (discardRowWriter) Close() error           { return nil }
+
+type discardDeadLetterWriter struct{}
+
+func (discardDeadLetterWriter) WriteDeadLetter(letter DeadLetter) error { return nil }
+func (discardDeadLetterWriter) Close() error                            { return nil }
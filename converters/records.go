@@ -0,0 +1,242 @@
+package converters
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Returned by the converters for records without an id, which can't be linked to anything
+var errMissingId = errors.New("missing id")
+
+// Gzipped JSON lines file of DeadLetter, only created once something is written to it
+type deadLetterWriter struct {
+	path    string
+	file    *os.File
+	archive *gzip.Writer
+	encoder *json.Encoder
+}
+
+func (d *deadLetterWriter) WriteDeadLetter(letter DeadLetter) error {
+	if d.file == nil {
+		if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+			return err
+		}
+
+		file, err := os.Create(d.path)
+		if err != nil {
+			return err
+		}
+		d.file = file
+		d.archive = gzip.NewWriter(file)
+		d.encoder = json.NewEncoder(d.archive)
+	}
+
+	return d.encoder.Encode(letter)
+}
+
+func (d *deadLetterWriter) Close() error {
+	if d.file == nil {
+		return nil
+	}
+
+	if err := d.archive.Close(); err != nil {
+		return err
+	}
+	return d.file.Close()
+}
+
+// Reads dead letter files back, keeping the source and line number of the original input
+func ReadDeadLetters(paths iter.Seq[string]) iter.Seq2[JsonLine, error] {
+	return func(yield func(JsonLine, error) bool) {
+		for path := range paths {
+			if !readDeadLetterFile(path, yield) {
+				return
+			}
+		}
+	}
+}
+
+func readDeadLetterFile(path string, yield func(JsonLine, error) bool) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return yield(JsonLine{Source: path}, err)
+	}
+	defer file.Close()
+
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		return yield(JsonLine{Source: path}, err)
+	}
+	defer gzReader.Close()
+
+	scanner := bufio.NewScanner(gzReader)
+	scanner.Buffer(nil, maxLineSize)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			if !yield(JsonLine{Source: path, Line: lineNumber}, err) {
+				return false
+			}
+			continue
+		}
+
+		raw := []byte(letter.Raw)
+		data, err := DecodeJsonLine(raw)
+		if !yield(JsonLine{Data: data, Source: letter.Source, Line: letter.Line, Raw: raw}, err) {
+			return false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return yield(JsonLine{Source: path, Line: lineNumber + 1}, err)
+	}
+	return true
+}
+
+// Converts a single record, turning a panic into an error
+func convertRecord(data map[string]any, convert func(data map[string]any) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return convert(data)
+}
+
+// Holds the rows a converter writes for a record until the record is accepted,
+// so that a rejected record leaves nothing behind in any of its tables
+type recordBuffer struct {
+	sink Sink
+	rows []bufferedRow
+}
+
+type bufferedRow struct {
+	writer RowWriter
+	row    Row
+}
+
+func newRecordBuffer(sink Sink) *recordBuffer {
+	return &recordBuffer{sink: sink}
+}
+
+func (b *recordBuffer) OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error) {
+	writer, err := b.sink.OpenTable(entity, table, chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedRowWriter{buffer: b, writer: writer}, nil
+}
+
+func (b *recordBuffer) selectedColumns(entity string, table TableSchema) []bool {
+	return selectedColumns(b.sink, entity, table)
+}
+
+// Writes the rows of the current record to the sink
+func (b *recordBuffer) commit() {
+	for _, row := range b.rows {
+		if err := row.writer.WriteRow(row.row); err != nil {
+			log.Println(err)
+		}
+	}
+	b.discard()
+}
+
+func (b *recordBuffer) discard() {
+	clear(b.rows)
+	b.rows = b.rows[:0]
+}
+
+type bufferedRowWriter struct {
+	buffer *recordBuffer
+	writer RowWriter
+}
+
+func (w *bufferedRowWriter) WriteRow(row Row) error {
+	w.buffer.rows = append(w.buffer.rows, bufferedRow{writer: w.writer, row: slices.Clone(row)})
+	return nil
+}
+
+func (w *bufferedRowWriter) Close() error {
+	return w.writer.Close()
+}
+
+// Runs convert for every record in lines that matches the entity's filters in options.
+// The converter's tables must be opened on buffer, which only passes the rows of a record
+// on to its sink once convert accepts the record.
+// Lines that can't be decoded and records that convert rejects or panics on are logged
+// and, if the sink keeps them, written to its dead letters, from where they can be reprocessed
+func forEachRecord(lines iter.Seq2[JsonLine, error], buffer *recordBuffer, entity string, chunk int, options *Options, convert func(data map[string]any) error) error {
+	sink := buffer.sink
+
+	var deadLetters DeadLetterWriter
+	if deadLetterSink, ok := sink.(DeadLetterSink); ok {
+		writer, err := deadLetterSink.OpenDeadLetters(entity, chunk)
+		if err != nil {
+			return err
+		}
+		deadLetters = writer
+
+		defer func() {
+			if err := deadLetters.Close(); err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+
+	reject := func(line JsonLine, err error) {
+		if line.Line == 0 {
+			log.Printf("%v: %v", line.Source, err)
+		} else {
+			log.Printf("%v:%v: %v", line.Source, line.Line, err)
+		}
+
+		// Errors opening or reading a file have no line to keep
+		if deadLetters == nil || line.Raw == nil {
+			return
+		}
+		if err := deadLetters.WriteDeadLetter(DeadLetter{
+			Source: line.Source,
+			Line:   line.Line,
+			Reason: err.Error(),
+			Raw:    string(line.Raw),
+		}); err != nil {
+			log.Println(err)
+		}
+	}
+
+	router := recordRouterOf(sink, entity)
+
+	for line, err := range lines {
+		if err != nil {
+			reject(line, err)
+			continue
+		}
+		if !options.matchesFilters(entity, line.Data) {
+			continue
+		}
+		if router != nil {
+			router.startRecord(entity, chunk, line.Data)
+		}
+
+		options.FieldAudit.observe(line.Data)
+		if err := convertRecord(line.Data, convert); err != nil {
+			buffer.discard()
+			reject(line, err)
+			continue
+		}
+		buffer.commit()
+	}
+	return nil
+}
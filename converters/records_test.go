@@ -0,0 +1,99 @@
+package converters
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeadLettersReprocess(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "part_000.gz")
+	records := []string{
+		`{"id": "W1", "ids": {"openalex": "W1"}, "referenced_works": ["W2"]}`,
+		`{"id": "W2", "ids": {"openalex": "W2"}, "referenced_works": ["W3"]}`,
+		`{"id": "W3", "ids": {"openalex": "W3"}, "referenced_works": nul}`,
+		`{"id": "W4", "ids": {"openalex": "W4"}, "referenced_works": ["W1", "W2"]}`,
+	}
+	writeGzipLines(t, input, records)
+
+	// Converting W2 panics halfway through its tables, which must neither stop the chunk nor leave rows behind
+	sink := NewCsvSink(filepath.Join(dir, "out"))
+	mapping := TypeWorks.mapping
+	buffer := newRecordBuffer(sink)
+	mw, err := openMapping(mapping, buffer, 0, NewOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	convert := func(data map[string]any) error {
+		err := mw.convert(data)
+		if data["id"] == "W2" {
+			panic("converter bug")
+		}
+		return err
+	}
+	if err := forEachRecord(ReadJsonLinesAll(slices.Values([]string{input})), buffer, "works", 0, NewOptions(), convert); err != nil {
+		t.Fatal(err)
+	}
+	mw.Close()
+
+	var ids []string
+	for _, part := range sink.TakeParts() {
+		for _, row := range readCsvPart(t, part.Path) {
+			if part.Table == "works" {
+				ids = append(ids, row[0])
+			}
+			if row[0] == "W2" {
+				t.Errorf("%v has a row of W2", part.Table)
+			}
+		}
+	}
+	if !slices.Equal(ids, []string{"W1", "W4"}) {
+		t.Errorf("converted %v, expected [W1 W4]", ids)
+	}
+
+	// Both rejected lines keep the input file and line they came from
+	deadLetters := filepath.Join(dir, "out", "works", "works_dead_letter0.jsonl.gz")
+	var lines []JsonLine
+	var errs []error
+	for line, err := range ReadDeadLetters(slices.Values([]string{deadLetters})) {
+		lines = append(lines, line)
+		errs = append(errs, err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("%v dead letters, expected 2", len(lines))
+	}
+	for i, line := range []int{2, 3} {
+		if lines[i].Source != input || lines[i].Line != line || string(lines[i].Raw) != records[line-1] {
+			t.Errorf("dead letter %v from %v:%v %q, expected line %v", i, lines[i].Source, lines[i].Line, lines[i].Raw, line)
+		}
+	}
+	if errs[0] != nil || errs[1] == nil {
+		t.Errorf("decoding the dead letters gave %v, expected only the malformed line to fail", errs)
+	}
+
+	// Reprocessing gives the panicking record the rows it would have had, and rejects the malformed line again
+	reprocessed := NewMemorySink()
+	if err := TypeWorks.ConvertWith(ReadDeadLetters(slices.Values([]string{deadLetters})), reprocessed, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	direct := NewMemorySink()
+	if err := TypeWorks.ConvertWith(jsonLines(t, records[1]), direct, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected, got := formatTables(direct), formatTables(reprocessed)
+	if len(expected["works"]) != 1 || len(expected["works_referenced_works"]) != 1 {
+		t.Fatalf("W2 converted to %v", expected)
+	}
+	for table, rows := range expected {
+		if !slices.Equal(got[table], rows) {
+			t.Errorf("%v: reprocessed %q, expected %q", table, got[table], rows)
+		}
+	}
+
+	rejected := reprocessed.DeadLetters()
+	if len(rejected) != 1 || rejected[0].Source != input || rejected[0].Line != 3 || !strings.Contains(rejected[0].Raw, `"W3"`) {
+		t.Errorf("reprocessing rejected %v, expected line 3 again", rejected)
+	}
+}
@@ -0,0 +1,69 @@
+package converters
+
+type Column struct {
+	Name string
+	// DuckDB type, like TEXT or INTEGER
+	Type string
+}
+
+type TableSchema struct {
+	Name    string
+	Columns []Column
+
+	// Create the table in the import script, for tables that aren't in openalex-duckdb-schema.sql
+	CreateTable bool
+}
+
+// A row of a table, one value per column.
+// Values are nil, string, json.Number, bool, int or json.RawMessage (for JSON columns)
+type Row []any
+
+type RowWriter interface {
+	WriteRow(row Row) error
+	Close() error
+}
+
+// Destination of the rows produced by the converters.
+// OpenTable is called once per table and chunk, from as many goroutines as there are chunks
+type Sink interface {
+	OpenTable(entity string, table TableSchema, chunk int) (RowWriter, error)
+}
+
+// Optionally implemented by sinks that only write some tables and columns,
+// so that converters can skip computing the others
+type columnSelector interface {
+	// Whether each column of table is written, nil if the table isn't
+	selectedColumns(entity string, table TableSchema) []bool
+}
+
+// Whether each column of table is written to sink, nil if the table isn't.
+// Sinks that aren't a columnSelector write every column
+func selectedColumns(sink Sink, entity string, table TableSchema) []bool {
+	if selector, ok := sink.(columnSelector); ok {
+		return selector.selectedColumns(entity, table)
+	}
+
+	selected := make([]bool, len(table.Columns))
+	for i := range selected {
+		selected[i] = true
+	}
+	return selected
+}
+
+// An input line that was rejected, along with where it came from and why
+type DeadLetter struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+	Raw    string `json:"raw"`
+}
+
+type DeadLetterWriter interface {
+	WriteDeadLetter(letter DeadLetter) error
+	Close() error
+}
+
+// Optionally implemented by sinks that keep rejected lines, which are otherwise only logged
+type DeadLetterSink interface {
+	OpenDeadLetters(entity string, chunk int) (DeadLetterWriter, error)
+}
@@ -0,0 +1,174 @@
+package converters
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+)
+
+// A path in records of one entity type holding ids of another
+type subsetReference struct {
+	entity string
+	path   string
+}
+
+// References followed when building a subset, by the entity type holding them.
+// Ancestors are followed through the lineage and ancestors lists, which already hold the whole chain,
+// so no entity type has to be read twice. Not followed are the works a work cites or is related to,
+// associated institutions, related concepts, sibling topics and the counterparts of funders and keywords,
+// which would pull in most of the snapshot
+var subsetReferences = map[string][]subsetReference{
+	"works": {
+		{"authors", "authorships.author.id"},
+		{"institutions", "authorships.institutions.id"},
+		{"institutions", "authorships.institutions.lineage"},
+		{"sources", "primary_location.source.id"},
+		{"sources", "locations.source.id"},
+		{"sources", "best_oa_location.source.id"},
+		{"topics", "primary_topic.id"},
+		{"topics", "topics.id"},
+		{"concepts", "concepts.id"},
+		{"keywords", "keywords.id"},
+		{"funders", "grants.funder"},
+	},
+	"authors": {
+		{"institutions", "last_known_institutions.id"},
+		{"institutions", "last_known_institutions.lineage"},
+		{"institutions", "affiliations.institution.id"},
+		{"institutions", "affiliations.institution.lineage"},
+	},
+	"sources": {
+		// Hold publishers or institutions, told apart by subsetIdPrefixes
+		{"publishers", "host_organization"},
+		{"institutions", "host_organization"},
+		{"publishers", "host_organization_lineage"},
+		{"institutions", "host_organization_lineage"},
+	},
+	"publishers": {
+		// A plain id in older snapshots, an object in current ones
+		{"publishers", "parent_publisher"},
+		{"publishers", "parent_publisher.id"},
+		{"publishers", "lineage"},
+	},
+	"topics": {
+		{"subfields", "subfield.id"},
+		{"fields", "field.id"},
+		{"domains", "domain.id"},
+	},
+	"concepts": {
+		{"concepts", "ancestors.id"},
+	},
+}
+
+// Prefix of the short ids of entity types referenced by paths shared with another entity type
+var subsetIdPrefixes = map[string]string{
+	"publishers":   "P",
+	"institutions": "I",
+}
+
+// Entity types whose references are collected, each before any entity type it references.
+// Publishers and concepts reference their own ancestors, whose lineage needs no further reading
+var subsetScanOrder = []string{"works", "authors", "sources", "publishers", "topics", "concepts"}
+
+// Ids are compared without the https://openalex.org/ prefix, so id lists can use either form
+func shortOpenAlexId(id string) string {
+	return strings.TrimPrefix(id, "https://openalex.org/")
+}
+
+// The records of a self-contained part of the snapshot: the seed works and everything they reference,
+// directly or through their authors, sources, publishers, topics and concepts
+type Subset struct {
+	mu  sync.Mutex
+	ids map[string]map[string]struct{}
+}
+
+func NewSubset() *Subset {
+	return &Subset{ids: map[string]map[string]struct{}{}}
+}
+
+func (s *Subset) Add(entity string, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.ids[entity]
+	if ids == nil {
+		ids = map[string]struct{}{}
+		s.ids[entity] = ids
+	}
+	ids[shortOpenAlexId(id)] = struct{}{}
+}
+
+func (s *Subset) Contains(entity string, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.ids[entity][shortOpenAlexId(id)]
+	return exists
+}
+
+// Number of ids collected for entity
+func (s *Subset) Len(entity string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.ids[entity])
+}
+
+// Entity types that are restricted to the subset, in the order their references are collected.
+// Other entity types aren't referenced by works and are converted in full
+func SubsetScanOrder() []string {
+	return subsetScanOrder
+}
+
+// Whether the subset decides which records of entity are converted
+func SubsetRestricts(entity string) bool {
+	for _, references := range subsetReferences {
+		for _, reference := range references {
+			if reference.entity == entity {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Adds the ids referenced by the records in lines that match the filters of entity in options.
+// Lines that can't be decoded are skipped, they are reported when converting
+func (s *Subset) Collect(entity string, lines iter.Seq2[JsonLine, error], options *Options) {
+	references := subsetReferences[entity]
+
+	for line, err := range lines {
+		if err != nil || !options.matchesFilters(entity, line.Data) {
+			continue
+		}
+
+		for _, reference := range references {
+			walkFilterPath(line.Data, splitPath(reference.path), func(value any) bool {
+				if id, ok := value.(string); ok && strings.HasPrefix(shortOpenAlexId(id), subsetIdPrefixes[reference.entity]) {
+					s.Add(reference.entity, id)
+				}
+				return true
+			})
+		}
+	}
+}
+
+type subsetFilter struct {
+	subset *Subset
+	entity string
+}
+
+func (f subsetFilter) match(record map[string]any) bool {
+	id, ok := record["id"].(string)
+	return ok && f.subset.Contains(f.entity, id)
+}
+
+// Filter matching the records of entity whose id is in the subset,
+// checked against the ids collected by the time each record is read
+func (s *Subset) Filter(entity string) *Filter {
+	return &Filter{
+		expression: fmt.Sprintf("id in subset of %v", entity),
+		root:       subsetFilter{subset: s, entity: entity},
+	}
+}
@@ -0,0 +1,107 @@
+package converters
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSubsetClosure(t *testing.T) {
+	input := map[string][]string{
+		"works": {
+			`{"id": "https://openalex.org/W1", "authorships": [{"author": {"id": "https://openalex.org/A1"}, "institutions": [{"id": "https://openalex.org/I1", "lineage": ["https://openalex.org/I1"]}]}],
+				"primary_location": {"source": {"id": "https://openalex.org/S1"}}, "topics": [{"id": "https://openalex.org/T1"}],
+				"concepts": [{"id": "https://openalex.org/C3"}], "referenced_works": ["https://openalex.org/W2"]}`,
+			`{"id": "https://openalex.org/W2", "authorships": [{"author": {"id": "https://openalex.org/A2"}}]}`,
+		},
+		"authors": {
+			`{"id": "https://openalex.org/A1",
+				"last_known_institutions": [{"id": "https://openalex.org/I3", "lineage": ["https://openalex.org/I3", "https://openalex.org/I2"]}],
+				"affiliations": [{"institution": {"id": "https://openalex.org/I4", "lineage": ["https://openalex.org/I4"]}}]}`,
+			`{"id": "https://openalex.org/A2", "last_known_institutions": [{"id": "https://openalex.org/I9"}]}`,
+		},
+		"sources": {
+			`{"id": "https://openalex.org/S1", "host_organization": "https://openalex.org/P3", "host_organization_lineage": ["https://openalex.org/P3"]}`,
+			`{"id": "https://openalex.org/S2", "host_organization": "https://openalex.org/P9"}`,
+		},
+		"publishers": {
+			`{"id": "https://openalex.org/P3", "parent_publisher": {"id": "https://openalex.org/P2"}, "lineage": ["https://openalex.org/P3", "https://openalex.org/P2", "https://openalex.org/P1"]}`,
+			`{"id": "https://openalex.org/P2", "parent_publisher": "https://openalex.org/P1"}`,
+			`{"id": "https://openalex.org/P9", "lineage": ["https://openalex.org/P8"]}`,
+		},
+		"topics": {
+			`{"id": "https://openalex.org/T1", "subfield": {"id": "https://openalex.org/subfields/1"}, "field": {"id": "https://openalex.org/fields/1"}, "domain": {"id": "https://openalex.org/domains/1"}}`,
+		},
+		"concepts": {
+			`{"id": "https://openalex.org/C3", "ancestors": [{"id": "https://openalex.org/C2"}, {"id": "https://openalex.org/C1"}], "related_concepts": [{"id": "https://openalex.org/C7"}]}`,
+			`{"id": "https://openalex.org/C8", "ancestors": [{"id": "https://openalex.org/C9"}]}`,
+		},
+	}
+
+	// Starts from W1 like -subset-ids, collecting references the way the command line tool does
+	subset := NewSubset()
+	subset.Add("works", "W1")
+	options := NewOptions()
+	options.AddFilter("works", subset.Filter("works"))
+	for _, entity := range []string{"authors", "institutions", "sources", "publishers", "topics", "subfields", "fields", "domains", "concepts"} {
+		if !SubsetRestricts(entity) {
+			t.Fatalf("%v not restricted to the subset", entity)
+		}
+		options.AddFilter(entity, subset.Filter(entity))
+	}
+	for _, entity := range SubsetScanOrder() {
+		subset.Collect(entity, jsonLines(t, input[entity]...), options)
+	}
+
+	tests := []struct {
+		entity string
+		ids    []string
+		// Referenced only by records outside the subset, or by references that aren't followed
+		excluded []string
+	}{
+		{entity: "works", ids: []string{"W1"}, excluded: []string{"W2"}},
+		{entity: "authors", ids: []string{"A1"}, excluded: []string{"A2"}},
+		{entity: "institutions", ids: []string{"I1", "I2", "I3", "I4"}, excluded: []string{"I9"}},
+		{entity: "sources", ids: []string{"S1"}, excluded: []string{"S2"}},
+		{entity: "publishers", ids: []string{"P1", "P2", "P3"}, excluded: []string{"P8", "P9"}},
+		{entity: "topics", ids: []string{"T1"}},
+		{entity: "subfields", ids: []string{"subfields/1"}},
+		{entity: "fields", ids: []string{"fields/1"}},
+		{entity: "domains", ids: []string{"domains/1"}},
+		{entity: "concepts", ids: []string{"C1", "C2", "C3"}, excluded: []string{"C7", "C8", "C9"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.entity, func(t *testing.T) {
+			for _, id := range test.ids {
+				if !subset.Contains(test.entity, id) {
+					t.Errorf("%v not in the subset", id)
+				}
+			}
+			for _, id := range test.excluded {
+				if subset.Contains(test.entity, id) {
+					t.Errorf("%v in the subset", id)
+				}
+			}
+			if subset.Len(test.entity) != len(test.ids) {
+				t.Errorf("%v ids, expected %v", subset.Len(test.entity), len(test.ids))
+			}
+		})
+	}
+}
+
+func TestSubsetScanOrder(t *testing.T) {
+	order := SubsetScanOrder()
+	for entity, references := range subsetReferences {
+		position := slices.Index(order, entity)
+		if position < 0 {
+			t.Errorf("references of %v are never collected", entity)
+			continue
+		}
+		// Every entity type holding references is scanned after the ones referencing it
+		for _, reference := range references {
+			if scanned := slices.Index(order, reference.entity); scanned >= 0 && scanned < position {
+				t.Errorf("%v is scanned before %v, which references it", reference.entity, entity)
+			}
+		}
+	}
+}
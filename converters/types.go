@@ -2,55 +2,23 @@ package converters
 
 import (
 	"encoding/json"
-	"iter"
+	"log"
 )
 
 type jsontype struct {
 	value any
 }
 
-func (j jsontype) MarshalCSV() ([]byte, error) {
-	return json.Marshal(j.value)
-}
-
-func tryCast[T any](value any) *T {
-	castValue, ok := value.(T)
-	if ok {
-		return &castValue
-	} else {
+// Raw JSON of the value, or nil for JSON null
+func (j jsontype) rawJson() any {
+	if j.value == nil {
 		return nil
 	}
-}
 
-func getCast[T any](m map[string]any, key string) *T {
-	valAny, exists := m[key]
-	if !exists {
+	raw, err := json.Marshal(j.value)
+	if err != nil {
+		log.Println(err)
 		return nil
 	}
-
-	return tryCast[T](valAny)
-}
-
-func iterCast[T any](arr []any) iter.Seq[*T] {
-	return func(yield func(*T) bool) {
-		for _, item := range arr {
-			itemCast := tryCast[T](item)
-			if itemCast != nil && !yield(itemCast) {
-				return
-			}
-		}
-	}
-}
-
-func getCastAt[T any](m map[string]any, path []string) *T {
-	var current any = m
-	for _, key := range path {
-		currentMap, ok := current.(map[string]any)
-		if !ok {
-			return nil
-		}
-		current = currentMap[key]
-	}
-
-	return tryCast[T](current)
+	return json.RawMessage(raw)
 }
@@ -17,7 +17,10 @@ CREATE TABLE openalex.authors (
     cited_by_count integer,
     last_known_institution text,
     works_api_url text,
-    updated_date timestamp without time zone
+    updated_date timestamp without time zone,
+    h_index integer,
+    i10_index integer,
+    two_yr_mean_citedness real
 );
 
 
@@ -49,6 +52,49 @@ CREATE TABLE openalex.authors_ids (
 );
 
 
+--
+-- Name: authors_affiliations; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.authors_affiliations (
+    author_id text,
+    institution_id text,
+    year integer
+);
+
+
+--
+-- Name: authors_last_known_institutions; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.authors_last_known_institutions (
+    author_id text,
+    institution_id text
+);
+
+
+--
+-- Name: authors_topics; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.authors_topics (
+    author_id text,
+    topic_id text,
+    count integer
+);
+
+
+--
+-- Name: authors_topic_share; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.authors_topic_share (
+    author_id text,
+    topic_id text,
+    value real
+);
+
+
 CREATE TABLE openalex.topics (
     id text NOT NULL,
     display_name text,
@@ -68,6 +114,200 @@ CREATE TABLE openalex.topics (
     siblings json
 );
 
+--
+-- Name: topics_keywords; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.topics_keywords (
+    topic_id text,
+    keyword text
+);
+
+
+--
+-- Name: topics_siblings; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.topics_siblings (
+    topic_id text,
+    sibling_topic_id text
+);
+
+
+--
+-- Name: topics_ids; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.topics_ids (
+    topic_id text NOT NULL,
+    openalex text,
+    wikipedia text,
+    wikidata text
+);
+
+
+--
+-- Name: keywords; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.keywords (
+    id text NOT NULL,
+    display_name text,
+    works_count integer,
+    cited_by_count integer,
+    works_api_url text,
+    created_date date,
+    updated_date timestamp without time zone
+);
+
+
+--
+-- Name: domains; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.domains (
+    id text NOT NULL,
+    display_name text,
+    display_name_alternatives json,
+    description text,
+    works_count integer,
+    cited_by_count integer,
+    works_api_url text,
+    updated_date timestamp without time zone
+);
+
+
+--
+-- Name: domains_ids; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.domains_ids (
+    domain_id text NOT NULL,
+    wikidata text,
+    wikipedia text
+);
+
+
+--
+-- Name: domains_fields; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.domains_fields (
+    domain_id text,
+    field_id text
+);
+
+
+--
+-- Name: domains_siblings; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.domains_siblings (
+    domain_id text,
+    sibling_domain_id text
+);
+
+
+--
+-- Name: fields; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.fields (
+    id text NOT NULL,
+    display_name text,
+    display_name_alternatives json,
+    description text,
+    domain_id text,
+    domain_display_name text,
+    works_count integer,
+    cited_by_count integer,
+    works_api_url text,
+    updated_date timestamp without time zone
+);
+
+
+--
+-- Name: fields_ids; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.fields_ids (
+    field_id text NOT NULL,
+    wikidata text,
+    wikipedia text
+);
+
+
+--
+-- Name: fields_subfields; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.fields_subfields (
+    field_id text,
+    subfield_id text
+);
+
+
+--
+-- Name: fields_siblings; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.fields_siblings (
+    field_id text,
+    sibling_field_id text
+);
+
+
+--
+-- Name: subfields; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.subfields (
+    id text NOT NULL,
+    display_name text,
+    display_name_alternatives json,
+    description text,
+    field_id text,
+    field_display_name text,
+    domain_id text,
+    domain_display_name text,
+    works_count integer,
+    cited_by_count integer,
+    works_api_url text,
+    updated_date timestamp without time zone
+);
+
+
+--
+-- Name: subfields_ids; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.subfields_ids (
+    subfield_id text NOT NULL,
+    wikidata text,
+    wikipedia text
+);
+
+
+--
+-- Name: subfields_topics; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.subfields_topics (
+    subfield_id text,
+    topic_id text
+);
+
+
+--
+-- Name: subfields_siblings; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.subfields_siblings (
+    subfield_id text,
+    sibling_subfield_id text
+);
+
+
 --
 -- Name: concepts; Type: TABLE; Schema: openalex; Owner: -
 --
@@ -83,7 +323,10 @@ CREATE TABLE openalex.concepts (
     image_url text,
     image_thumbnail_url text,
     works_api_url text,
-    updated_date timestamp without time zone
+    updated_date timestamp without time zone,
+    h_index integer,
+    i10_index integer,
+    two_yr_mean_citedness real
 );
 
 
@@ -136,6 +379,18 @@ CREATE TABLE openalex.concepts_related_concepts (
 );
 
 
+--
+-- Name: concepts_international; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.concepts_international (
+    concept_id text,
+    language text,
+    display_name text,
+    description text
+);
+
+
 --
 -- Name: institutions; Type: TABLE; Schema: openalex; Owner: -
 --
@@ -154,7 +409,10 @@ CREATE TABLE openalex.institutions (
     works_count integer,
     cited_by_count integer,
     works_api_url text,
-    updated_date timestamp without time zone
+    updated_date timestamp without time zone,
+    h_index integer,
+    i10_index integer,
+    two_yr_mean_citedness real
 );
 
 
@@ -213,6 +471,79 @@ CREATE TABLE openalex.institutions_ids (
 );
 
 
+--
+-- Name: institutions_lineage; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.institutions_lineage (
+    institution_id text,
+    ancestor_id text
+);
+
+
+--
+-- Name: institutions_lineage_depths; Type: VIEW; Schema: openalex; Owner: -
+--
+-- Depth of each ancestor, 0 for the institution itself. Lineage lists no order, so the depth
+-- is the number of institutions in the lineage that have the ancestor in their own lineage, less one.
+-- Exact as long as there is one path up to the ancestor and every institution on it was imported
+--
+
+CREATE VIEW openalex.institutions_lineage_depths AS
+SELECT lineage.institution_id, lineage.ancestor_id, count(*) - 1 AS depth
+FROM openalex.institutions_lineage lineage
+JOIN openalex.institutions_lineage path ON path.institution_id = lineage.institution_id
+JOIN openalex.institutions_lineage below ON below.institution_id = path.ancestor_id AND below.ancestor_id = lineage.ancestor_id
+GROUP BY lineage.institution_id, lineage.ancestor_id;
+
+
+--
+-- Name: institutions_roles; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.institutions_roles (
+    institution_id text,
+    role text,
+    role_id text,
+    works_count integer
+);
+
+
+--
+-- Name: institutions_repositories; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.institutions_repositories (
+    institution_id text,
+    repository_id text,
+    display_name text,
+    host_organization text,
+    host_organization_name text
+);
+
+
+--
+-- Name: institutions_international_names; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.institutions_international_names (
+    institution_id text,
+    language text,
+    display_name text
+);
+
+
+--
+-- Name: institutions_topics; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.institutions_topics (
+    institution_id text,
+    topic_id text,
+    count integer
+);
+
+
 --
 -- Name: publishers; Type: TABLE; Schema: openalex; Owner: -
 --
@@ -227,7 +558,14 @@ CREATE TABLE openalex.publishers (
     works_count integer,
     cited_by_count integer,
     sources_api_url text,
-    updated_date timestamp without time zone
+    updated_date timestamp without time zone,
+    parent_publisher_display_name text,
+    image_url text,
+    image_thumbnail_url text,
+    homepage_url text,
+    h_index integer,
+    i10_index integer,
+    two_yr_mean_citedness real
 );
 
 
@@ -256,6 +594,42 @@ CREATE TABLE openalex.publishers_ids (
 );
 
 
+--
+-- Name: publishers_lineage; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.publishers_lineage (
+    publisher_id text,
+    ancestor_id text
+);
+
+
+--
+-- Name: publishers_lineage_depths; Type: VIEW; Schema: openalex; Owner: -
+--
+-- Depth of each ancestor, 0 for the publisher itself, counted like institutions_lineage_depths
+--
+
+CREATE VIEW openalex.publishers_lineage_depths AS
+SELECT lineage.publisher_id, lineage.ancestor_id, count(*) - 1 AS depth
+FROM openalex.publishers_lineage lineage
+JOIN openalex.publishers_lineage path ON path.publisher_id = lineage.publisher_id
+JOIN openalex.publishers_lineage below ON below.publisher_id = path.ancestor_id AND below.ancestor_id = lineage.ancestor_id
+GROUP BY lineage.publisher_id, lineage.ancestor_id;
+
+
+--
+-- Name: publishers_roles; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.publishers_roles (
+    publisher_id text,
+    role text,
+    role_id text,
+    works_count integer
+);
+
+
 --
 -- Name: sources; Type: TABLE; Schema: openalex; Owner: -
 --
@@ -272,7 +646,18 @@ CREATE TABLE openalex.sources (
     is_in_doaj boolean,
     homepage_url text,
     works_api_url text,
-    updated_date timestamp without time zone
+    updated_date timestamp without time zone,
+    type text,
+    country_code text,
+    host_organization text,
+    host_organization_name text,
+    alternate_titles json,
+    abbreviated_title text,
+    apc_usd integer,
+    is_core boolean,
+    h_index integer,
+    i10_index integer,
+    two_yr_mean_citedness real
 );
 
 
@@ -304,6 +689,107 @@ CREATE TABLE openalex.sources_ids (
 );
 
 
+--
+-- Name: sources_apc_prices; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.sources_apc_prices (
+    source_id text,
+    price integer,
+    currency text
+);
+
+
+--
+-- Name: sources_societies; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.sources_societies (
+    source_id text,
+    url text,
+    organization text
+);
+
+
+--
+-- Name: sources_host_organization_lineage; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.sources_host_organization_lineage (
+    source_id text,
+    host_organization_id text
+);
+
+
+--
+-- Name: sources_topics; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.sources_topics (
+    source_id text,
+    topic_id text,
+    count integer
+);
+
+
+--
+-- Name: funders; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.funders (
+    id text NOT NULL,
+    display_name text,
+    alternate_titles json,
+    country_code text,
+    description text,
+    homepage_url text,
+    image_url text,
+    image_thumbnail_url text,
+    grants_count integer,
+    works_count integer,
+    cited_by_count integer,
+    updated_date timestamp without time zone
+);
+
+
+--
+-- Name: funders_counts_by_year; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.funders_counts_by_year (
+    funder_id text NOT NULL,
+    year integer NOT NULL,
+    works_count integer,
+    cited_by_count integer
+);
+
+
+--
+-- Name: funders_ids; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.funders_ids (
+    funder_id text NOT NULL,
+    openalex text,
+    ror text,
+    wikidata text,
+    crossref text,
+    doi text
+);
+
+
+--
+-- Name: funders_roles; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.funders_roles (
+    funder_id text,
+    role text,
+    role_id text,
+    works_count integer
+);
+
+
 --
 -- Name: works; Type: TABLE; Schema: openalex; Owner: -
 --
@@ -321,7 +807,16 @@ CREATE TABLE openalex.works (
     is_paratext boolean,
     cited_by_api_url text,
     abstract_inverted_index json,
-    language text
+    language text,
+    type_crossref text,
+    indexed_in json,
+    has_fulltext boolean,
+    fulltext_origin text,
+    countries_distinct_count integer,
+    institutions_distinct_count integer,
+    locations_count integer,
+    created_date date,
+    updated_date timestamp without time zone
 );
 
 --
@@ -331,11 +826,18 @@ CREATE TABLE openalex.works (
 CREATE TABLE openalex.works_primary_locations (
     work_id text,
     source_id text,
+    source_display_name text,
+    source_type text,
+    source_issn_l text,
+    source_host_organization text,
+    source_host_organization_name text,
     landing_page_url text,
     pdf_url text,
     is_oa boolean,
     version text,
-    license text
+    license text,
+    is_accepted boolean,
+    is_published boolean
 );
 
 
@@ -345,12 +847,20 @@ CREATE TABLE openalex.works_primary_locations (
 
 CREATE TABLE openalex.works_locations (
     work_id text,
+    location_index integer,
     source_id text,
+    source_display_name text,
+    source_type text,
+    source_issn_l text,
+    source_host_organization text,
+    source_host_organization_name text,
     landing_page_url text,
     pdf_url text,
     is_oa boolean,
     version text,
-    license text
+    license text,
+    is_accepted boolean,
+    is_published boolean
 );
 
 
@@ -361,11 +871,18 @@ CREATE TABLE openalex.works_locations (
 CREATE TABLE openalex.works_best_oa_locations (
     work_id text,
     source_id text,
+    source_display_name text,
+    source_type text,
+    source_issn_l text,
+    source_host_organization text,
+    source_host_organization_name text,
     landing_page_url text,
     pdf_url text,
     is_oa boolean,
     version text,
-    license text
+    license text,
+    is_accepted boolean,
+    is_published boolean
 );
 
 
@@ -476,6 +993,20 @@ CREATE TABLE openalex.works_related_works (
 );
 
 
+--
+-- Name: works_apc; Type: TABLE; Schema: openalex; Owner: -
+--
+
+CREATE TABLE openalex.works_apc (
+    work_id text,
+    kind text,
+    value integer,
+    currency text,
+    value_usd integer,
+    provenance text
+);
+
+
 ----
 ---- Name: authors_counts_by_year authors_counts_by_year_pkey; Type: CONSTRAINT; Schema: openalex; Owner: -
 ----